@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/netscrn/homm3utils/defparse"
+)
+
+func runBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	format := fs.Int("format", -1, "frames compression format 0-3, meta.json format is used by default")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: defutils build [flags] <extracted def dir> <out .def file>")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	def, ofm, err := defparse.LoadExtractedDef(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defFormat := ofm.Format
+	if *format >= 0 {
+		defFormat = uint32(*format)
+	}
+	err = defparse.WriteDefFile(fs.Arg(1), def, defFormat)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Built %s with %d blocks in format %d\n", fs.Arg(1), len(def.Blocks), defFormat)
+	return nil
+}
@@ -1,20 +1,44 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/netscrn/homm3utils/defparse"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 func main() {
-	if len(os.Args) > 3 {
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		err := runBuild(os.Args[2:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "build: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	indexed := flag.Bool("indexed", false, "write paletted pngs keeping the def palette and special color indexes")
+	players := flag.String("players", "", "also write frames in colors of comma separated players or all of them with \"all\"")
+	playersPal := flag.String("players-pal", "", "PLAYERS.PAL with exact player colors, approximated ones are used by default")
+	anim := flag.Bool("anim", false, "also write every block as animated gif and apng")
+	delay := flag.Duration("delay", 100*time.Millisecond, "delay between animation frames")
+	flag.Parse()
+	if flag.NArg() != 2 {
 		panic("invalid arguments")
 	}
+	playerColors, playerPalettes, err := parsePlayersFlags(*players, *playersPal)
+	if err != nil {
+		panic(err)
+	}
+	opts := defparse.ExtractOptions{Indexed: *indexed, Players: playerColors, PlayerPalettes: playerPalettes}
+	if *anim {
+		opts.Animation = &defparse.AnimationOptions{Delay: *delay}
+	}
 
-	dirContent, err := os.ReadDir(os.Args[1])
+	dirContent, err := os.ReadDir(flag.Arg(0))
 	if err != nil {
 		panic(err)
 	}
@@ -37,7 +61,7 @@ func main() {
 
 			for _, entry := range dirContent[start:end] {
 				if strings.HasSuffix(entry.Name(), ".def") {
-					err := defparse.ExtractDef(filepath.Join(os.Args[1], entry.Name()), os.Args[2])
+					err := defparse.ExtractDefWithOptions(filepath.Join(flag.Arg(0), entry.Name()), flag.Arg(1), opts)
 					if err != nil {
 						fmt.Printf("Can't extract %s: %v\n", entry.Name(), err)
 						continue
@@ -47,4 +71,36 @@ func main() {
 		}()
 	}
 	wg.Wait()
-}
\ No newline at end of file
+}
+
+func parsePlayersFlags(players, playersPal string) ([]defparse.PlayerColor, *defparse.PlayerPalettes, error) {
+	if players == "" {
+		return nil, nil, nil
+	}
+	var playerColors []defparse.PlayerColor
+	if players == "all" {
+		playerColors = defparse.AllPlayerColors
+	} else {
+		for _, name := range strings.Split(players, ",") {
+			player, err := defparse.ParsePlayerColor(strings.TrimSpace(name))
+			if err != nil {
+				return nil, nil, err
+			}
+			playerColors = append(playerColors, player)
+		}
+	}
+	if playersPal == "" {
+		fmt.Fprintln(os.Stderr, "warning: player colors are approximated, pass -players-pal with PLAYERS.PAL for the game colors")
+		return playerColors, nil, nil
+	}
+	palFile, err := os.Open(playersPal)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer palFile.Close()
+	palettes, err := defparse.ReadPlayerPalettes(palFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read player palettes(%s): %w", playersPal, err)
+	}
+	return playerColors, palettes, nil
+}
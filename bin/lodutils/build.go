@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils build <recipe .json file> <src dir> <out .lod file>")
+		fmt.Fprintln(fs.Output(), "the recipe is lod archive meta json, files are written in its order and compressed when compressed_size isn't 0")
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 3 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	recipe, err := lodparse.LoadLodArchiveMetaFromJson(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("can't load lod recipe(%s): %w", fs.Arg(0), err)
+	}
+	lam, err := lodparse.BuildLodArchive(recipe, fs.Arg(1), fs.Arg(2))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Built %d files into %s\n", lam.NumberOfFiles, lam.ArchiveFilePath)
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "print differences as json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils diff [flags] <old .lod file> <new .lod file>")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	oldArchive, err := lodparse.LoadLodArchiveMetaFromLodFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newArchive, err := lodparse.LoadLodArchiveMetaFromLodFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	diff, err := lodparse.DiffLodArchives(oldArchive, newArchive)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "    ")
+		return jsonEncoder.Encode(diff)
+	}
+	for _, entry := range diff.Entries {
+		fmt.Println(entry)
+	}
+	fmt.Printf("%d files differ, %d files unchanged\n", len(diff.Entries), diff.Unchanged)
+	return nil
+}
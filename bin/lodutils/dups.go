@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runDups(args []string) error {
+	fs := flag.NewFlagSet("dups", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "print the report as json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils dups [flags] <.lod file>...")
+		fmt.Fprintln(fs.Output(), "reports files with identical content and names used for different content across archives")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	archives := make([]*lodparse.LodArchiveMeta, 0, fs.NArg())
+	for _, pathToLod := range fs.Args() {
+		lam, err := lodparse.LoadLodArchiveMetaFromLodFile(pathToLod)
+		if err != nil {
+			return err
+		}
+		archives = append(archives, lam)
+	}
+	report, err := lodparse.FindDuplicates(archives)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "    ")
+		return jsonEncoder.Encode(report)
+	}
+	for _, group := range report.Duplicates {
+		fmt.Printf("duplicate %s (%d bytes, %d copies):\n", group.SHA256, group.Size, len(group.Files))
+		for _, file := range group.Files {
+			fmt.Printf("    %s: %s\n", file.Archive, file.Name)
+		}
+	}
+	for _, conflict := range report.Conflicts {
+		fmt.Printf("conflict %s:\n", conflict.Name)
+		for _, file := range conflict.Files {
+			fmt.Printf("    %s: %s (%d bytes, %s)\n", file.Archive, file.Name, file.Size, file.SHA256)
+		}
+	}
+	fmt.Printf("%d duplicate groups (%d bytes wasted), %d name conflicts\n", len(report.Duplicates), report.WastedBytes, len(report.Conflicts))
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runAdd(args []string) error {
+	return runEdit("add", args, func(lam *lodparse.LodArchiveMeta, arg string, compression lodparse.LodCompression) error {
+		return lam.Add(pathSource(arg, compression))
+	})
+}
+
+func runReplace(args []string) error {
+	return runEdit("replace", args, func(lam *lodparse.LodArchiveMeta, arg string, compression lodparse.LodCompression) error {
+		return lam.Replace(pathSource(arg, compression))
+	})
+}
+
+func runRemove(args []string) error {
+	return runEdit("rm", args, func(lam *lodparse.LodArchiveMeta, arg string, _ lodparse.LodCompression) error {
+		return lam.Remove(arg)
+	})
+}
+
+// runEdit applies edit to each argument after the archive path and saves the archive
+func runEdit(name string, args []string, edit func(*lodparse.LodArchiveMeta, string, lodparse.LodCompression) error) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	outPath := fs.String("o", "", "path of the saved archive, the archive is changed in place by default")
+	compressionArg := fs.String("compress", "auto", "compression of new content: auto, always or never")
+	fs.Usage = func() {
+		if name == "rm" {
+			fmt.Fprintln(fs.Output(), "usage: lodutils rm [flags] <.lod file> <file names...>")
+		} else {
+			fmt.Fprintf(fs.Output(), "usage: lodutils %s [flags] <.lod file> <files...>\n", name)
+		}
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+	compression, err := parseCompression(*compressionArg)
+	if err != nil {
+		return err
+	}
+
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, arg := range fs.Args()[1:] {
+		err = edit(lam, arg, compression)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *outPath == "" {
+		*outPath = fs.Arg(0)
+	}
+	err = lam.Save(*outPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved %d files into %s\n", lam.NumberOfFiles, lam.ArchiveFilePath)
+	return nil
+}
+
+func pathSource(path string, compression lodparse.LodCompression) lodparse.LodFileSource {
+	return lodparse.LodFileSource{
+		Name:        filepath.Base(path),
+		Compression: compression,
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}
+}
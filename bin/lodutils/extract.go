@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/netscrn/homm3utils/lodconv"
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runExtract(args []string) error {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	concurrencyLevel := fs.Int("c", 0, "number of extracting goroutines, 0 means default")
+	continueOnError := fs.Bool("continue", false, "continue extracting after a file fails")
+	verbose := fs.Bool("v", false, "print each extracted file")
+	var filter lodparse.LodFileFilter
+	fs.Var((*stringsFlag)(&filter.Include), "include", "extract only files matching the glob pattern, may be repeated")
+	fs.Var((*stringsFlag)(&filter.Exclude), "exclude", "skip files matching the glob pattern, may be repeated")
+	fs.Var((*stringsFlag)(&filter.Extensions), "ext", "extract only files with the extension, may be repeated or comma separated")
+	fs.Var((*stringsFlag)(&filter.Names), "name", "extract only the named file, may be repeated or comma separated")
+	minSize := fs.Uint("min-size", 0, "extract only files of at least this original size")
+	maxSize := fs.Uint("max-size", 0, "extract only files of at most this original size, 0 means no limit")
+	var convert []string
+	fs.Var((*stringsFlag)(&convert), "convert", "convert files of the format (def, pcx, txt) instead of extracting them raw, may be repeated or comma separated")
+	keepRaw := fs.Bool("keep-raw", false, "write raw files alongside converted ones")
+	codepage := fs.String("codepage", "1251", "codepage of converted txt files, 1251 or 1252")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils extract [flags] <.lod file> <out dir> [concurrency level]")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	pathToLod, dstDir, err := validateAndGetArgs(fs)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 3 {
+		*concurrencyLevel, err = strconv.Atoi(fs.Arg(2))
+		if err != nil {
+			return errors.New("invalid third argument, should be int")
+		}
+	}
+
+	if *minSize > math.MaxUint32 || *maxSize > math.MaxUint32 {
+		return fmt.Errorf("size filters can't exceed %d, the largest size of lod files", uint32(math.MaxUint32))
+	}
+	filter.MinSize, filter.MaxSize = uint32(*minSize), uint32(*maxSize)
+	err = filter.Validate()
+	if err != nil {
+		return err
+	}
+
+	converters, err := extractConverters(convert, *codepage)
+	if err != nil {
+		return err
+	}
+
+	lodArchiveMeta, err := lodparse.LoadLodArchiveMetaFromLodFile(pathToLod)
+	if err != nil {
+		return err
+	}
+	for _, name := range filter.Names {
+		if _, err := lodArchiveMeta.GetFile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s is not in %s\n", name, pathToLod)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	last := lodparse.ExtractProgress{FilesTotal: len(lodArchiveMeta.Filter(filter.Match))}
+	if last.FilesTotal == 0 {
+		return fmt.Errorf("no files of %s match the filters", pathToLod)
+	}
+	opts := lodparse.ExtractOptions{
+		Filter:           filter.Match,
+		ConcurrencyLevel: *concurrencyLevel,
+		ContinueOnError:  *continueOnError,
+		Converters:       converters,
+		KeepRaw:          *keepRaw,
+		Progress: func(p lodparse.ExtractProgress) {
+			last = p
+			if *verbose {
+				fmt.Printf("[%d/%d] %s\n", p.FilesDone, p.FilesTotal, p.File.Name)
+			}
+		},
+	}
+	err = lodparse.ExtractLodFilesContext(ctx, lodArchiveMeta, dstDir, opts)
+
+	var extractErrs lodparse.ExtractErrors
+	if errors.As(err, &extractErrs) {
+		for _, e := range extractErrs {
+			fmt.Fprintln(os.Stderr, e.Error())
+		}
+	}
+	fmt.Printf("Extracted %d of %d files (%d bytes)\n", last.FilesDone, last.FilesTotal, last.BytesWritten)
+	if len(extractErrs) != 0 {
+		return fmt.Errorf("%d files failed", len(extractErrs))
+	}
+	return err
+}
+
+func extractConverters(formats []string, codepage string) (map[string]lodparse.LodFileConverter, error) {
+	if len(formats) == 0 {
+		return nil, nil
+	}
+	converters := make(map[string]lodparse.LodFileConverter, len(formats))
+	for _, format := range formats {
+		switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+		case "def":
+			converters[".def"] = lodconv.DefToPNG()
+		case "pcx":
+			converters[".pcx"] = lodconv.PcxToPNG()
+		case "txt":
+			cp, err := lodconv.CodepageByName(codepage)
+			if err != nil {
+				return nil, err
+			}
+			converters[".txt"] = lodconv.TxtToUTF8(cp)
+		default:
+			return nil, fmt.Errorf("can't convert %s files, supported formats are def, pcx and txt", format)
+		}
+	}
+	return converters, nil
+}
+
+func validateAndGetArgs(fs *flag.FlagSet) (pathToLod string, dstDir string, err error) {
+	if fs.NArg() < 2 || fs.NArg() > 3 {
+		fs.Usage()
+		return "", "", errors.New("wrong arguments count")
+	}
+
+	pathToLod = fs.Arg(0)
+	if len(pathToLod) == 0 {
+		return "", "", errors.New("first argument is empty, should be path to .lod file")
+	}
+	pathToLod, err = filepath.Abs(pathToLod)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := os.Stat(pathToLod); errors.Is(err, os.ErrNotExist) {
+		return "", "", errors.New("no file exists by path: " + pathToLod)
+	}
+
+	dstDir = fs.Arg(1)
+	if len(dstDir) == 0 {
+		return "", "", errors.New("second argument is empty, should be a path to output dir")
+	}
+	dstDirInfo, err := os.Stat(dstDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", errors.New("no directory exists by path: " + dstDir)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if !dstDirInfo.IsDir() {
+		return "", "", errors.New(dstDir + ": is not directory")
+	}
+
+	return pathToLod, dstDir, nil
+}
+
+// stringsFlag collects values of a repeated flag, each value may hold several comma separated items
+type stringsFlag []string
+
+func (sf *stringsFlag) String() string {
+	if sf == nil {
+		return ""
+	}
+	return strings.Join(*sf, ",")
+}
+
+func (sf *stringsFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item != "" {
+			*sf = append(*sf, item)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runIdentify(args []string) error {
+	fs := flag.NewFlagSet("identify", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "print identities as json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils identify [flags] <.lod file>...")
+		fmt.Fprintln(fs.Output(), "tells the game release (roe, ab, sod) or hota language archive each archive comes from,\ncomplete edition archives are told as sod, wog and hota.lod aren't identified")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	identities := make(map[string]lodparse.LodIdentity, fs.NArg())
+	for _, pathToLod := range fs.Args() {
+		lam, err := lodparse.LoadLodArchiveMetaFromLodFile(pathToLod)
+		if err != nil {
+			return err
+		}
+		identity, err := lam.Identify()
+		if err != nil {
+			return err
+		}
+		identities[pathToLod] = identity
+		if !*jsonOutput {
+			fmt.Printf("%s: %s\n", pathToLod, identity)
+		}
+	}
+
+	if *jsonOutput {
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "    ")
+		return jsonEncoder.Encode(identities)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	pattern := fs.String("glob", "*", "list only files matching the pattern, case is ignored")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils list [flags] <.lod file>")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	files, err := lam.Glob(*pattern)
+	if err != nil {
+		return fmt.Errorf("invalid glob pattern(%s): %w", *pattern, err)
+	}
+
+	for _, file := range files {
+		fmt.Printf("%-16s %10d %10d %10d\n", file.Name, file.Offset, file.OriginalSize, file.CompressedSize)
+	}
+
+	for _, group := range lam.CaseCollisions() {
+		names := make([]string, 0, len(group))
+		for _, file := range group {
+			names = append(names, file.Name)
+		}
+		fmt.Fprintf(os.Stderr, "case collision: %s\n", strings.Join(names, ", "))
+	}
+	return nil
+}
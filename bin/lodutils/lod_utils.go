@@ -2,65 +2,50 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strconv"
-
-	"github.com/netscrn/homm3utils/lodparse"
 )
 
-func main() {
-	pathToLod, dstDir, concurrencyLevel := validateAndGetArgs()
+type command func(args []string) error
+
+var commands = map[string]command{
+	"add":            runAdd,
+	"build":          runBuild,
+	"check-manifest": runCheckManifest,
+	"diff":           runDiff,
+	"dups":           runDups,
+	"extract":        runExtract,
+	"identify":       runIdentify,
+	"keygen":         runKeygen,
+	"list":           runList,
+	"manifest":       runManifest,
+	"pack":           runPack,
+	"replace":        runReplace,
+	"rm":             runRemove,
+	"verify":         runVerify,
+}
 
-	lodArchiveMeta, err := lodparse.LoadLodArchiveMetaFromLodFile(pathToLod)
-	if err != nil {
-		panic(err.Error())
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: lodutils [command] <args>, commands: extract (default), add, build, check-manifest, diff, dups, identify, keygen, list, manifest, pack, replace, rm, verify")
+		os.Exit(2)
 	}
 
-	err = lodparse.ExtractLodFiles(lodArchiveMeta, dstDir, concurrencyLevel)
-	if err != nil {
-		panic(err.Error())
+	// without a known command the arguments are treated as extract arguments
+	name, cmd, args := "extract", runExtract, os.Args[1:]
+	if c, ok := commands[os.Args[1]]; ok {
+		name, cmd, args = os.Args[1], c, os.Args[2:]
 	}
-
-	fmt.Print("Done")
+	exitOnError(name, cmd(args))
 }
 
-func validateAndGetArgs() (pathToLod string, dstDir string, concurrencyLevel int) {
-	if len(os.Args) < 3 {
-		panic("\n Wrong arguments count \nfirst argument should be path to .lod file. \nsecond argument should be a path to output dir")
-	}
-
-	pathToLod = os.Args[1]
-	if len(pathToLod) == 0 {
-		panic("first argument is empty, should be path to .lod file")
+func exitOnError(name string, err error) {
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
 	}
-	pathToLod, err := filepath.Abs(pathToLod)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
 	}
-	if _, err := os.Stat(pathToLod); errors.Is(err, os.ErrNotExist) {
-		panic("no file exists by path: " + pathToLod)
-	}
-
-	dstDir = os.Args[2]
-	if len(dstDir) == 0 {
-		panic("second argument is empty, should be a path to output dir")
-	}
-	dstDirInfo, err := os.Stat(dstDir)
-	if errors.Is(err, os.ErrNotExist) {
-		panic("no directory exists by path: " + pathToLod)
-	}
-	if !dstDirInfo.IsDir() {
-		panic(dstDir + ": is not directory")
-	}
-
-	if len(os.Args) == 4 {
-		concurrencyLevel, err = strconv.Atoi(os.Args[3])
-		if err != nil {
-			panic("invalid third argument, should be int")
-		}
-	}
-
-	return pathToLod, dstDir, concurrencyLevel
-}
\ No newline at end of file
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runManifest(args []string) error {
+	fs := flag.NewFlagSet("manifest", flag.ContinueOnError)
+	keyPath := fs.String("key", "", "path to private key file created by keygen, the manifest is signed with it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils manifest [flags] <.lod file> <out manifest .json file>")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	manifest, err := lodparse.NewLodManifest(lam)
+	if err != nil {
+		return err
+	}
+	if *keyPath != "" {
+		privateKey, err := readKey(*keyPath, ed25519.PrivateKeySize)
+		if err != nil {
+			return err
+		}
+		err = manifest.Sign(ed25519.PrivateKey(privateKey))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = manifest.WriteJsonFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Manifest of %d files is written into %s\n", len(manifest.Files), fs.Arg(1))
+	return nil
+}
+
+func runCheckManifest(args []string) error {
+	fs := flag.NewFlagSet("check-manifest", flag.ContinueOnError)
+	pubKeyPath := fs.String("pubkey", "", "path to public key file, the manifest signature is required and checked with it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils check-manifest [flags] <manifest .json file> <.lod file>")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	manifest, err := lodparse.LoadLodManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *pubKeyPath != "" {
+		publicKey, err := readKey(*pubKeyPath, ed25519.PublicKeySize)
+		if err != nil {
+			return err
+		}
+		err = manifest.VerifySignature(ed25519.PublicKey(publicKey))
+		if err != nil {
+			return err
+		}
+		fmt.Println("Manifest signature is valid")
+	} else {
+		fmt.Fprintln(os.Stderr, "warning: manifest signature isn't checked, pass -pubkey to check it")
+	}
+
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	installed, err := lodparse.NewLodManifest(lam)
+	if err != nil {
+		return err
+	}
+	if installed.SHA256 == manifest.SHA256 {
+		fmt.Printf("%s matches the manifest\n", lam.ArchiveFilePath)
+		return nil
+	}
+
+	mismatches, err := manifest.Check(lam)
+	if err != nil {
+		return err
+	}
+	for _, mismatch := range mismatches {
+		fmt.Println(mismatch)
+	}
+	if len(mismatches) != 0 {
+		return fmt.Errorf("%d files of %s don't match the manifest", len(mismatches), lam.ArchiveFilePath)
+	}
+	fmt.Printf("all files of %s match the manifest, but the archive layout differs\n", lam.ArchiveFilePath)
+	return nil
+}
+
+func runKeygen(args []string) error {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: lodutils keygen <out private key file> <out public key file>")
+		return errors.New("wrong arguments count")
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(args[0], []byte(base64.StdEncoding.EncodeToString(privateKey)+"\n"), 0600)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(args[1], []byte(base64.StdEncoding.EncodeToString(publicKey)+"\n"), 0644)
+}
+
+func readKey(path string, size int) ([]byte, error) {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("can't decode key(%s): %w", path, err)
+	}
+	if len(key) != size {
+		return nil, fmt.Errorf("key(%s) has wrong size %d, expected %d", path, len(key), size)
+	}
+	return key, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runPack(args []string) error {
+	fs := flag.NewFlagSet("pack", flag.ContinueOnError)
+	lodTypeArg := fs.String("type", "base", "lod archive type: base, expansion or a number, like 0x1F4")
+	compressionArg := fs.String("compress", "auto", "entries compression: auto, always or never")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils pack [flags] <src dir> <out .lod file>")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	lodType, err := lodparse.ParseLodArchiveType(*lodTypeArg)
+	if err != nil {
+		return err
+	}
+	compression, err := parseCompression(*compressionArg)
+	if err != nil {
+		return err
+	}
+
+	lam, err := lodparse.PackLodDir(fs.Arg(0), fs.Arg(1), lodType, compression)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Packed %d files into %s\n", lam.NumberOfFiles, lam.ArchiveFilePath)
+	return nil
+}
+
+func parseCompression(arg string) (lodparse.LodCompression, error) {
+	switch strings.ToLower(arg) {
+	case "auto":
+		return lodparse.CompressAuto, nil
+	case "always":
+		return lodparse.CompressAlways, nil
+	case "never":
+		return lodparse.CompressNever, nil
+	}
+	return 0, fmt.Errorf("invalid compression(%s)", arg)
+}
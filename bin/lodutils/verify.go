@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "print problems as json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lodutils verify [flags] <.lod file>")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	problems, err := lam.Verify()
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		if problems == nil {
+			problems = []lodparse.VerifyProblem{}
+		}
+		jsonEncoder := json.NewEncoder(os.Stdout)
+		jsonEncoder.SetIndent("", "    ")
+		err = jsonEncoder.Encode(problems)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("%d problems found in %s", len(problems), lam.ArchiveFilePath)
+	}
+	if !*jsonOutput {
+		fmt.Printf("%s: %d files are ok\n", lam.ArchiveFilePath, lam.NumberOfFiles)
+	}
+	return nil
+}
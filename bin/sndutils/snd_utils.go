@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/netscrn/homm3utils/internal/rawarchive"
+	"github.com/netscrn/homm3utils/internal/rawcli"
+	"github.com/netscrn/homm3utils/sndparse"
+)
+
+func main() {
+	rawcli.Tool{
+		Name:      "sndutils",
+		Extension: ".snd",
+		Load: func(pathToSnd string) (*rawarchive.Archive, error) {
+			sam, err := sndparse.LoadSndArchiveMetaFromSndFile(pathToSnd)
+			if err != nil {
+				return nil, err
+			}
+			return &sam.Archive, nil
+		},
+	}.Main()
+}
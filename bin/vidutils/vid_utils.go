@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/netscrn/homm3utils/internal/rawarchive"
+	"github.com/netscrn/homm3utils/internal/rawcli"
+	"github.com/netscrn/homm3utils/vidparse"
+)
+
+func main() {
+	rawcli.Tool{
+		Name:      "vidutils",
+		Extension: ".vid",
+		Load: func(pathToVid string) (*rawarchive.Archive, error) {
+			vam, err := vidparse.LoadVidArchiveMetaFromVidFile(pathToVid)
+			if err != nil {
+				return nil, err
+			}
+			return &vam.Archive, nil
+		},
+	}.Main()
+}
@@ -0,0 +1,258 @@
+package defparse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"time"
+)
+
+const defaultAnimationDelay = 100 * time.Millisecond
+
+// AnimationOptions tune block animations
+type AnimationOptions struct {
+	// Delay is shown between frames, 100ms is used when it's 0
+	Delay time.Duration
+}
+
+func (ao AnimationOptions) delay() time.Duration {
+	if ao.Delay <= 0 {
+		return defaultAnimationDelay
+	}
+	return ao.Delay
+}
+
+// errNoFrames and errEmptyFrames tell blocks which have nothing to animate
+var (
+	errNoFrames    = errors.New("no frames")
+	errEmptyFrames = errors.New("empty frames only")
+)
+
+// specialAlphas are alphas ExtractDef gives to special colors, they become transparent black
+var specialAlphas = map[color.RGBA]uint8{
+	background:            0,
+	shadowBorder:          64,
+	shadowBody:            128,
+	selectionShadowBody:   128,
+	selectionShadowBorder: 64,
+}
+
+// EncodeGIF writes block frames as a looped animated gif. Gif has no half transparency, so the background
+// and shadows are fully transparent. All frames are drawn on a canvas of the biggest frame size.
+func (db DefBlock) EncodeGIF(w io.Writer, opts AnimationOptions) error {
+	frames, err := db.animationFrames()
+	if err != nil {
+		return err
+	}
+
+	// gif takes the first transparent color as the transparent one, others are drawn as opaque
+	palette := animationPalette(frames[0].Palette)
+	transparent := make(map[uint8]bool)
+	transparentIndex := -1
+	for i, c := range palette {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			palette[i] = color.NRGBA{}
+			transparent[uint8(i)] = true
+			if transparentIndex == -1 {
+				transparentIndex = i
+			}
+		}
+	}
+	// gif delay is in hundredths of a second, viewers play zero delay at full speed
+	delay := int(opts.delay() / (10 * time.Millisecond))
+	if delay < 1 {
+		delay = 1
+	}
+	anim := gif.GIF{
+		Config: image.Config{ColorModel: palette, Width: frames[0].Rect.Dx(), Height: frames[0].Rect.Dy()},
+	}
+	for _, frame := range frames {
+		frame.Palette = palette
+		for i, index := range frame.Pix {
+			if transparent[index] {
+				frame.Pix[i] = uint8(transparentIndex)
+			}
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delay)
+		// frames are drawn on a cleared canvas, otherwise transparent pixels show previous frames
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+
+	err = gif.EncodeAll(w, &anim)
+	if err != nil {
+		return fmt.Errorf("can't encode gif of block(%d): %w", db.Id, err)
+	}
+	return nil
+}
+
+// EncodeAPNG writes block frames as a looped animated png, background is transparent and shadows are
+// half transparent black the same way ExtractDef writes them. All frames are drawn on a canvas of the biggest frame size.
+// Viewers without apng support show the first frame.
+func (db DefBlock) EncodeAPNG(w io.Writer, opts AnimationOptions) error {
+	frames, err := db.animationFrames()
+	if err != nil {
+		return err
+	}
+
+	// delay is written in milliseconds, it's limited by uint16
+	delayMs := opts.delay().Milliseconds()
+	if delayMs > 0xffff {
+		delayMs = 0xffff
+	}
+
+	aw := apngWriter{w: w}
+	aw.write([]byte("\x89PNG\r\n\x1a\n"))
+	palette := animationPalette(frames[0].Palette)
+	for i, frame := range frames {
+		frame.Palette = palette
+		var encoded bytes.Buffer
+		err := png.Encode(&encoded, frame)
+		if err != nil {
+			return fmt.Errorf("can't encode png frame(%d) of block(%d): %w", i, db.Id, err)
+		}
+		chunks, err := readPngChunks(encoded.Bytes())
+		if err != nil {
+			return fmt.Errorf("can't read png frame(%d) of block(%d): %w", i, db.Id, err)
+		}
+
+		// frames share the palette, so headers of the first frame are valid for all of them
+		if i == 0 {
+			for _, chunk := range chunks {
+				if chunk.kind == "IHDR" || chunk.kind == "PLTE" || chunk.kind == "tRNS" {
+					aw.writeChunk(chunk.kind, chunk.data)
+				}
+			}
+			actl := make([]byte, 8)
+			binary.BigEndian.PutUint32(actl[0:4], uint32(len(frames)))
+			// 0 plays is an endless loop
+			binary.BigEndian.PutUint32(actl[4:8], 0)
+			aw.writeChunk("acTL", actl)
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:4], aw.nextSequence())
+		binary.BigEndian.PutUint32(fctl[4:8], uint32(frame.Rect.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:12], uint32(frame.Rect.Dy()))
+		binary.BigEndian.PutUint16(fctl[20:22], uint16(delayMs))
+		binary.BigEndian.PutUint16(fctl[22:24], 1000)
+		// x and y offsets, dispose op none and blend op source are zeros, frames cover the whole canvas
+		aw.writeChunk("fcTL", fctl)
+
+		for _, chunk := range chunks {
+			if chunk.kind != "IDAT" {
+				continue
+			}
+			if i == 0 {
+				aw.writeChunk("IDAT", chunk.data)
+				continue
+			}
+			fdat := make([]byte, 4, 4+len(chunk.data))
+			binary.BigEndian.PutUint32(fdat, aw.nextSequence())
+			aw.writeChunk("fdAT", append(fdat, chunk.data...))
+		}
+	}
+	aw.writeChunk("IEND", nil)
+
+	if aw.err != nil {
+		return fmt.Errorf("can't write apng of block(%d): %w", db.Id, aw.err)
+	}
+	return nil
+}
+
+// animationFrames draws frames with their own palette indexes on a canvas of the biggest frame size
+func (db DefBlock) animationFrames() ([]*image.Paletted, error) {
+	if len(db.Frames) == 0 {
+		return nil, fmt.Errorf("block(%d) has %w", db.Id, errNoFrames)
+	}
+	var canvas image.Rectangle
+	for _, frame := range db.Frames {
+		canvas = canvas.Union(image.Rect(0, 0, int(frame.Meta.FullWight), int(frame.Meta.FullHeight)))
+	}
+	if canvas.Empty() {
+		return nil, fmt.Errorf("block(%d) has %w", db.Id, errEmptyFrames)
+	}
+
+	frames := make([]*image.Paletted, 0, len(db.Frames))
+	for _, frame := range db.Frames {
+		img := image.NewPaletted(canvas, frame.Image.Palette)
+		copyIndexes(img, frame.Image)
+		frames = append(frames, img)
+	}
+	return frames, nil
+}
+
+// animationPalette replaces special colors with transparent black the same way ExtractDef does
+func animationPalette(palette color.Palette) color.Palette {
+	animPalette := append(color.Palette(nil), palette...)
+	for i, c := range animPalette {
+		if alpha, ok := specialAlphas[color.RGBAModel.Convert(c).(color.RGBA)]; ok {
+			animPalette[i] = color.NRGBA{A: alpha}
+		}
+	}
+	return animPalette
+}
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+func readPngChunks(encoded []byte) ([]pngChunk, error) {
+	const signatureSize = 8
+	if len(encoded) < signatureSize {
+		return nil, errors.New("png is too short")
+	}
+	var chunks []pngChunk
+	rest := encoded[signatureSize:]
+	for len(rest) != 0 {
+		if len(rest) < 12 {
+			return nil, errors.New("truncated png chunk")
+		}
+		length := binary.BigEndian.Uint32(rest[0:4])
+		if uint64(len(rest)) < 12+uint64(length) {
+			return nil, errors.New("truncated png chunk")
+		}
+		chunks = append(chunks, pngChunk{kind: string(rest[4:8]), data: rest[8 : 8+length]})
+		rest = rest[12+length:]
+	}
+	return chunks, nil
+}
+
+// apngWriter writes png chunks keeping the first error and the apng sequence number shared by fcTL and fdAT
+type apngWriter struct {
+	w        io.Writer
+	sequence uint32
+	err      error
+}
+
+func (aw *apngWriter) nextSequence() uint32 {
+	sequence := aw.sequence
+	aw.sequence++
+	return sequence
+}
+
+func (aw *apngWriter) write(b []byte) {
+	if aw.err == nil {
+		_, aw.err = aw.w.Write(b)
+	}
+}
+
+func (aw *apngWriter) writeChunk(kind string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], kind)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:8])
+	crc.Write(data)
+	aw.write(header)
+	aw.write(data)
+	aw.write(crc.Sum(nil))
+}
@@ -0,0 +1,333 @@
+package defparse
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// specialColors are palette colors of indexes 0-7 used when a palette has to be built from frames
+var specialColors = [specialColorsCount]color.RGBA{
+	background,
+	shadowBorder,
+	{R: 255, G: 100, B: 255, A: 255},
+	{R: 255, G: 50, B: 255, A: 255},
+	shadowBody,
+	selection,
+	selectionShadowBody,
+	selectionShadowBorder,
+}
+
+// noFramesFormat is the format ExtractDef leaves in meta.json of a def without frames
+const noFramesFormat = 99999
+
+// BuildDef encodes the ExtractDef output of defOutDir into a def file at defPath in the format of meta.json
+func BuildDef(defOutDir, defPath string) error {
+	def, ofm, err := LoadExtractedDef(defOutDir)
+	if err != nil {
+		return err
+	}
+	format := ofm.Format
+	if format == noFramesFormat {
+		for _, block := range def.Blocks {
+			if len(block.Frames) != 0 {
+				return errors.New("def meta has frames, but no format of them")
+			}
+		}
+		// no frame is encoded, so any format fits
+		format = 0
+	}
+	return WriteDefFile(defPath, def, format)
+}
+
+// WriteDefFile encodes def into a new file at defPath
+func WriteDefFile(defPath string, def *Def, format uint32) error {
+	defFile, err := os.Create(defPath)
+	if err != nil {
+		return fmt.Errorf("can't create def file: %w", err)
+	}
+	bw := bufio.NewWriter(defFile)
+	err = EncodeDef(bw, def, format)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if closeErr := defFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(defPath)
+		return fmt.Errorf("can't write def file(%s): %w", defPath, err)
+	}
+	return nil
+}
+
+// LoadExtractedDef reads meta.json and png frames written by ExtractDef back into a Def.
+// Indexes of paletted frames having the def palette are kept as is. Otherwise transparent and shadow pixels
+// are mapped back to special palette indexes, other colors to the nearest palette color.
+// When meta.json has no palette, it's built of special colors and the most used frame colors.
+func LoadExtractedDef(defOutDir string) (*Def, *OutFilesMeta, error) {
+	ofmFile, err := os.Open(filepath.Join(defOutDir, "meta.json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't open def meta: %w", err)
+	}
+	defer ofmFile.Close()
+	var ofm OutFilesMeta
+	err = json.NewDecoder(ofmFile).Decode(&ofm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't decode def meta: %w", err)
+	}
+
+	frames := make(map[string]image.Image)
+	var framesOrder []image.Image
+	for _, bm := range ofm.BlocksMeta {
+		for _, di := range bm.DefImages {
+			path := extractedFramePath(defOutDir, bm.Id, di.Name)
+			if _, ok := frames[path]; ok {
+				continue
+			}
+			img, err := readPng(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			frames[path] = img
+			framesOrder = append(framesOrder, img)
+		}
+	}
+
+	var palette color.Palette
+	if len(ofm.Palette) != 0 {
+		palette, err = paletteFromHex(ofm.Palette)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		palette = buildPalette(framesOrder)
+	}
+
+	def := Def{
+		Type:    ofm.DefType,
+		Width:   ofm.Width,
+		Height:  ofm.Height,
+		Palette: palette,
+		Blocks:  make([]DefBlock, 0, len(ofm.BlocksMeta)),
+	}
+	mapper := newPaletteMapper(palette)
+	for _, bm := range ofm.BlocksMeta {
+		block := DefBlock{Id: bm.Id, Frames: make([]DefFrame, 0, len(bm.DefImages))}
+		for _, di := range bm.DefImages {
+			img := mapper.toPaletted(frames[extractedFramePath(defOutDir, bm.Id, di.Name)])
+			block.Frames = append(block.Frames, DefFrame{
+				Name: di.Name,
+				Meta: ImageMeta{
+					Format:     ofm.Format,
+					FullWight:  uint32(img.Rect.Dx()),
+					FullHeight: uint32(img.Rect.Dy()),
+					Width:      uint32(img.Rect.Dx()),
+					Height:     uint32(img.Rect.Dy()),
+				},
+				Image: img,
+			})
+			if uint32(img.Rect.Dx()) > def.Width && ofm.Width == 0 {
+				def.Width = uint32(img.Rect.Dx())
+			}
+			if uint32(img.Rect.Dy()) > def.Height && ofm.Height == 0 {
+				def.Height = uint32(img.Rect.Dy())
+			}
+		}
+		def.Blocks = append(def.Blocks, block)
+	}
+
+	return &def, &ofm, nil
+}
+
+func extractedFramePath(defOutDir string, blockId uint32, frameName string) string {
+	srcImgName := filepath.Base(strings.TrimSuffix(frameName, filepath.Ext(frameName)))
+	return filepath.Join(defOutDir, strconv.Itoa(int(blockId)), srcImgName+".png")
+}
+
+func readPng(path string) (image.Image, error) {
+	pngFile, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open frame: %w", err)
+	}
+	defer pngFile.Close()
+	img, err := png.Decode(bufio.NewReader(pngFile))
+	if err != nil {
+		return nil, fmt.Errorf("can't decode frame(%s): %w", path, err)
+	}
+	return img, nil
+}
+
+func paletteToHex(palette color.Palette) []string {
+	colors := make([]string, 0, len(palette))
+	for _, c := range palette {
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		colors = append(colors, hex.EncodeToString([]byte{rgba.R, rgba.G, rgba.B}))
+	}
+	return colors
+}
+
+func paletteFromHex(colors []string) (color.Palette, error) {
+	if len(colors) > 256 {
+		return nil, fmt.Errorf("palette has %d colors, more than 256", len(colors))
+	}
+	palette := make(color.Palette, 0, 256)
+	for _, c := range colors {
+		rgb, err := hex.DecodeString(c)
+		if err != nil || len(rgb) != 3 {
+			return nil, fmt.Errorf("invalid palette color(%s)", c)
+		}
+		palette = append(palette, color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255})
+	}
+	for len(palette) < 256 {
+		palette = append(palette, color.RGBA{A: 255})
+	}
+	return palette, nil
+}
+
+// buildPalette puts special colors first and the most used opaque frame colors after them
+func buildPalette(frames []image.Image) color.Palette {
+	counts := make(map[color.RGBA]int)
+	for _, img := range frames {
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				if c.A == 255 {
+					counts[c]++
+				}
+			}
+		}
+	}
+	for _, special := range specialColors {
+		delete(counts, special)
+	}
+
+	used := make([]color.RGBA, 0, len(counts))
+	for c := range counts {
+		used = append(used, c)
+	}
+	sort.Slice(used, func(i, j int) bool {
+		if counts[used[i]] != counts[used[j]] {
+			return counts[used[i]] > counts[used[j]]
+		}
+		return rgbaLess(used[i], used[j])
+	})
+
+	palette := make(color.Palette, 0, 256)
+	for _, special := range specialColors {
+		palette = append(palette, special)
+	}
+	for _, c := range used {
+		if len(palette) == 256 {
+			break
+		}
+		palette = append(palette, c)
+	}
+	for len(palette) < 256 {
+		palette = append(palette, color.RGBA{A: 255})
+	}
+	return palette
+}
+
+func rgbaLess(a, b color.RGBA) bool {
+	if a.R != b.R {
+		return a.R < b.R
+	}
+	if a.G != b.G {
+		return a.G < b.G
+	}
+	return a.B < b.B
+}
+
+// paletteMapper maps colors of extracted frames to palette indexes, ExtractDef replaces
+// the background with full transparency and shadows with black of 64 and 128 alpha
+type paletteMapper struct {
+	palette color.Palette
+	indexes map[color.RGBA]uint8
+}
+
+func newPaletteMapper(palette color.Palette) *paletteMapper {
+	pm := paletteMapper{palette: palette, indexes: make(map[color.RGBA]uint8)}
+	// regular colors win over special ones when they are equal
+	for i := len(palette) - 1; i >= specialColorsCount; i-- {
+		pm.indexes[color.RGBAModel.Convert(palette[i]).(color.RGBA)] = uint8(i)
+	}
+	// ExtractDef keeps selection and unnamed special colors opaque, they are matched when no regular color is equal
+	for i := 0; i < specialColorsCount && i < len(palette); i++ {
+		c := color.RGBAModel.Convert(palette[i]).(color.RGBA)
+		if _, ok := pm.indexes[c]; !ok {
+			pm.indexes[c] = uint8(i)
+		}
+	}
+	return &pm
+}
+
+func (pm *paletteMapper) toPaletted(img image.Image) *image.Paletted {
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(image.Rect(0, 0, bounds.Dx(), bounds.Dy()), pm.palette)
+	if src, ok := img.(*image.Paletted); ok && pm.samePalette(src.Palette) {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			copy(paletted.Pix[paletted.PixOffset(0, y-bounds.Min.Y):], src.Pix[src.PixOffset(bounds.Min.X, y):src.PixOffset(bounds.Max.X, y)])
+		}
+		return paletted
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			paletted.SetColorIndex(x-bounds.Min.X, y-bounds.Min.Y, pm.index(color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)))
+		}
+	}
+	return paletted
+}
+
+// samePalette tells if indexes of a frame palette point to the same colors, editors may drop unused trailing colors
+func (pm *paletteMapper) samePalette(palette color.Palette) bool {
+	if len(palette) > len(pm.palette) {
+		return false
+	}
+	for i, c := range palette {
+		r, g, b, _ := c.RGBA()
+		pr, pg, pb, _ := pm.palette[i].RGBA()
+		if r != pr || g != pg || b != pb {
+			return false
+		}
+	}
+	return true
+}
+
+func (pm *paletteMapper) index(c color.RGBA) uint8 {
+	switch {
+	case c.A < 32:
+		return 0
+	case c.A < 96:
+		return 1
+	case c.A < 255:
+		return 4
+	}
+	if index, ok := pm.indexes[c]; ok {
+		return index
+	}
+
+	var nearest uint8
+	bestDist := -1
+	for i := specialColorsCount; i < len(pm.palette); i++ {
+		pr, pg, pb, _ := pm.palette[i].RGBA()
+		dr, dg, db := int(pr>>8)-int(c.R), int(pg>>8)-int(c.G), int(pb>>8)-int(c.B)
+		dist := dr*dr + dg*dg + db*db
+		if bestDist == -1 || dist < bestDist {
+			nearest, bestDist = uint8(i), dist
+		}
+	}
+	pm.indexes[c] = nearest
+	return nearest
+}
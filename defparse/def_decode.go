@@ -0,0 +1,129 @@
+package defparse
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// maxDefFramePixels limits frame dimensions read from frame headers, so a corrupted header doesn't exhaust memory
+const maxDefFramePixels = 1 << 24
+
+// Def is a def file decoded into memory, frames keep palette indexes, so special colors can be told apart
+type Def struct {
+	Type    uint32
+	Width   uint32
+	Height  uint32
+	Palette color.Palette
+	Blocks  []DefBlock
+}
+
+type DefBlock struct {
+	Id     uint32
+	Frames []DefFrame
+}
+
+type DefFrame struct {
+	Name   string
+	Offset uint32
+	Meta   ImageMeta
+	// Image holds Meta.Width x Meta.Height pixels, its bounds are placed at the frame margins
+	// inside the Meta.FullWight x Meta.FullHeight frame
+	Image *image.Paletted
+}
+
+// DecodeDef reads the whole def from r, frames of all blocks are decoded
+func DecodeDef(r io.ReaderAt) (*Def, error) {
+	defFile := io.NewSectionReader(r, 0, math.MaxInt64)
+	defType, width, height, defBlocksCount, err := readDefMeta(defFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read def header: %w", err)
+	}
+
+	palette, err := readDefPalette(defFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read def palette: %w", err)
+	}
+
+	defBlocksMeta, err := readDefBlocksMeta(defFile, defBlocksCount)
+	if err != nil {
+		return nil, fmt.Errorf("can't read def blocks meta: %w", err)
+	}
+
+	def := Def{
+		Type:    defType,
+		Width:   width,
+		Height:  height,
+		Palette: *palette,
+		Blocks:  make([]DefBlock, 0, len(*defBlocksMeta)),
+	}
+	for _, bm := range *defBlocksMeta {
+		block := DefBlock{Id: bm.Id, Frames: make([]DefFrame, 0, len(bm.DefImages))}
+		for _, di := range bm.DefImages {
+			frame, err := decodeFrame(defFile, di, def.Palette)
+			if err != nil {
+				return nil, fmt.Errorf("can't decode frame(%s) of block(%d): %w", di.Name, bm.Id, err)
+			}
+			block.Frames = append(block.Frames, *frame)
+		}
+		def.Blocks = append(def.Blocks, block)
+	}
+
+	return &def, nil
+}
+
+func decodeFrame(defFile io.ReadSeeker, di DefImage, palette color.Palette) (*DefFrame, error) {
+	_, err := defFile.Seek(int64(di.Offset), io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("can't seek to offset(%d): %w", di.Offset, err)
+	}
+	imgMeta, err := readImageMeta(defFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read meta: %w", err)
+	}
+
+	pixelsCount := uint64(imgMeta.Width) * uint64(imgMeta.Height)
+	if pixelsCount > maxDefFramePixels || uint64(imgMeta.FullWight)*uint64(imgMeta.FullHeight) > maxDefFramePixels {
+		return nil, fmt.Errorf("dimensions(%dx%d) are too large", imgMeta.FullWight, imgMeta.FullHeight)
+	}
+
+	margin := image.Pt(int(imgMeta.LeftMargin), int(imgMeta.TopMargin))
+	rect := image.Rectangle{Min: margin, Max: margin.Add(image.Pt(int(imgMeta.Width), int(imgMeta.Height)))}
+	img := image.NewPaletted(rect, palette)
+	if pixelsCount != 0 {
+		pixels, err := readPixels(defFile, di, imgMeta)
+		if err != nil {
+			return nil, fmt.Errorf("can't read pixels: %w", err)
+		}
+		if uint64(len(pixels)) != pixelsCount {
+			return nil, fmt.Errorf("decoded %d pixels, but frame has %d", len(pixels), pixelsCount)
+		}
+		img.Pix = pixels
+	}
+
+	return &DefFrame{Name: di.Name, Offset: di.Offset, Meta: *imgMeta, Image: img}, nil
+}
+
+// FullImage returns the frame drawn on a FullWight x FullHeight canvas filled with the background palette index 0
+func (df *DefFrame) FullImage() *image.Paletted {
+	full := image.NewPaletted(image.Rect(0, 0, int(df.Meta.FullWight), int(df.Meta.FullHeight)), df.Image.Palette)
+	copyIndexes(full, df.Image)
+	return full
+}
+
+// copyIndexes copies indexes of src pixels inside dst bounds, drawing would match colors and mix up indexes of equal colors
+func copyIndexes(dst, src *image.Paletted) {
+	visible := dst.Rect.Intersect(src.Rect)
+	for y := visible.Min.Y; y < visible.Max.Y; y++ {
+		copy(dst.Pix[dst.PixOffset(visible.Min.X, y):dst.PixOffset(visible.Max.X, y)],
+			src.Pix[src.PixOffset(visible.Min.X, y):])
+	}
+}
+
+// RGBA returns the full frame with special colors replaced by transparency and shadows, the same way ExtractDef does
+func (df *DefFrame) RGBA() *image.RGBA {
+	meta := df.Meta
+	return decodePixels(df.Image.Pix, df.Image.Palette, &meta)
+}
@@ -0,0 +1,285 @@
+package defparse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+const (
+	defHeaderSize      = 16
+	defPaletteSize     = 256 * 3
+	defBlockHeaderSize = 16
+	defFrameNameSize   = 13
+	defFrameHeaderSize = 32
+	// specialColorsCount is the number of palette indexes used as transparency, shadow and selection in formats 1-3
+	specialColorsCount = 8
+)
+
+// EncodeDef writes def into w with all frames encoded in the format, which is one of 0-3.
+// Frame bounds and meta dimensions are taken from Image and Meta.FullWight x Meta.FullHeight,
+// transparent borders of formats 1 and 2 frames are cropped into margins.
+// Frames with the same name are stored once, the same way game defs reuse frames, so they have to be equal.
+func EncodeDef(w io.Writer, def *Def, format uint32) error {
+	if format > 3 {
+		return fmt.Errorf("unknown format(%d)", format)
+	}
+	if len(def.Palette) > 256 {
+		return fmt.Errorf("palette has %d colors, more than 256", len(def.Palette))
+	}
+
+	framesOffset := defHeaderSize + defPaletteSize
+	for _, block := range def.Blocks {
+		framesOffset += defBlockHeaderSize + (defFrameNameSize+4)*len(block.Frames)
+	}
+
+	var frames bytes.Buffer
+	offsets := make(map[string]uint32)
+	stored := make(map[string]DefFrame)
+	for _, block := range def.Blocks {
+		for _, frame := range block.Frames {
+			if len(frame.Name) >= defFrameNameSize {
+				return fmt.Errorf("frame name(%s) is longer than %d characters", frame.Name, defFrameNameSize-1)
+			}
+			if storedFrame, ok := stored[frame.Name]; ok {
+				if !sameFrames(storedFrame, frame) {
+					return fmt.Errorf("frame(%s) of block(%d) differs from another frame of the same name, rename one of them", frame.Name, block.Id)
+				}
+				continue
+			}
+			stored[frame.Name] = frame
+			offsets[frame.Name] = uint32(framesOffset + frames.Len())
+			err := encodeFrame(&frames, frame, format)
+			if err != nil {
+				return fmt.Errorf("can't encode frame(%s) of block(%d): %w", frame.Name, block.Id, err)
+			}
+		}
+	}
+
+	var header bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, [4]uint32{def.Type, def.Width, def.Height, uint32(len(def.Blocks))})
+	palette := make([]byte, defPaletteSize)
+	for i, c := range def.Palette {
+		r, g, b, _ := c.RGBA()
+		palette[i*3], palette[i*3+1], palette[i*3+2] = uint8(r>>8), uint8(g>>8), uint8(b>>8)
+	}
+	header.Write(palette)
+	for _, block := range def.Blocks {
+		// 8 bytes following the frames count have unknown meaning and are left zeroed
+		binary.Write(&header, binary.LittleEndian, [4]uint32{block.Id, uint32(len(block.Frames)), 0, 0})
+		for _, frame := range block.Frames {
+			name := make([]byte, defFrameNameSize)
+			copy(name, frame.Name)
+			header.Write(name)
+		}
+		for _, frame := range block.Frames {
+			binary.Write(&header, binary.LittleEndian, offsets[frame.Name])
+		}
+	}
+
+	_, err := w.Write(header.Bytes())
+	if err != nil {
+		return fmt.Errorf("can't write def header: %w", err)
+	}
+	_, err = w.Write(frames.Bytes())
+	if err != nil {
+		return fmt.Errorf("can't write def frames: %w", err)
+	}
+	return nil
+}
+
+// sameFrames tells if frames have equal full dimensions and pixel indexes
+func sameFrames(a, b DefFrame) bool {
+	if a.Meta.FullWight != b.Meta.FullWight || a.Meta.FullHeight != b.Meta.FullHeight || a.Image.Rect != b.Image.Rect {
+		return false
+	}
+	rect := a.Image.Rect
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		rowA := a.Image.Pix[a.Image.PixOffset(rect.Min.X, y):a.Image.PixOffset(rect.Max.X, y)]
+		rowB := b.Image.Pix[b.Image.PixOffset(rect.Min.X, y):b.Image.PixOffset(rect.Max.X, y)]
+		if !bytes.Equal(rowA, rowB) {
+			return false
+		}
+	}
+	return true
+}
+
+func encodeFrame(w *bytes.Buffer, frame DefFrame, format uint32) error {
+	img := frame.Image
+	rect := img.Rect
+	if format == 1 || format == 2 {
+		rect = opaqueBounds(img)
+	}
+	if format == 3 && rect.Dx()%32 != 0 {
+		return fmt.Errorf("format 3 frame width(%d) is not a multiple of 32", rect.Dx())
+	}
+	if rect.Min.X < 0 || rect.Min.Y < 0 || rect.Max.X > int(frame.Meta.FullWight) || rect.Max.Y > int(frame.Meta.FullHeight) {
+		return fmt.Errorf("frame bounds %v are outside of full dimensions(%dx%d)", rect, frame.Meta.FullWight, frame.Meta.FullHeight)
+	}
+
+	rows := make([][]uint8, 0, rect.Dy())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		start := img.PixOffset(rect.Min.X, y)
+		rows = append(rows, img.Pix[start:start+rect.Dx()])
+	}
+
+	var data []byte
+	var err error
+	switch format {
+	case 0:
+		for _, row := range rows {
+			data = append(data, row...)
+		}
+	case 1:
+		data, err = encodeFormat1Pixels(rows)
+	case 2:
+		data, err = encodeFormat2Pixels(rows)
+	case 3:
+		data, err = encodeFormat3Pixels(rows)
+	}
+	if err != nil {
+		return err
+	}
+
+	binary.Write(w, binary.LittleEndian, [6]uint32{
+		uint32(len(data)), format, frame.Meta.FullWight, frame.Meta.FullHeight, uint32(rect.Dx()), uint32(rect.Dy()),
+	})
+	binary.Write(w, binary.LittleEndian, [2]int32{int32(rect.Min.X), int32(rect.Min.Y)})
+	w.Write(data)
+	return nil
+}
+
+// opaqueBounds returns the smallest rectangle holding all pixels except the background index 0
+func opaqueBounds(img *image.Paletted) image.Rectangle {
+	minX, minY, maxX, maxY := img.Rect.Max.X, img.Rect.Max.Y, img.Rect.Min.X, img.Rect.Min.Y
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(img.Rect.Min.X, y):img.PixOffset(img.Rect.Max.X, y)]
+		for i, index := range row {
+			if index == 0 {
+				continue
+			}
+			x := img.Rect.Min.X + i
+			if x < minX {
+				minX = x
+			}
+			if x >= maxX {
+				maxX = x + 1
+			}
+			if y < minY {
+				minY = y
+			}
+			maxY = y + 1
+		}
+	}
+	if minX >= maxX {
+		return image.Rectangle{}
+	}
+	return image.Rect(minX, minY, maxX, maxY)
+}
+
+// encodeFormat1Pixels writes uint32 line offsets followed by lines of segments, a segment is a code and a length-1.
+// Special colors are stored as runs with the color as the code, other colors as raw bytes with the 0xff code.
+func encodeFormat1Pixels(rows [][]uint8) ([]byte, error) {
+	var lines bytes.Buffer
+	lineOffs := make([]uint32, 0, len(rows))
+	for _, row := range rows {
+		lineOffs = append(lineOffs, uint32(4*len(rows)+lines.Len()))
+		for _, seg := range splitSegments(row, specialColorsCount, 256) {
+			if seg.raw {
+				lines.WriteByte(0xff)
+				lines.WriteByte(uint8(len(seg.pixels) - 1))
+				lines.Write(seg.pixels)
+			} else {
+				lines.WriteByte(seg.pixels[0])
+				lines.WriteByte(uint8(len(seg.pixels) - 1))
+			}
+		}
+	}
+
+	data := make([]byte, 4*len(rows), 4*len(rows)+lines.Len())
+	for i, off := range lineOffs {
+		binary.LittleEndian.PutUint32(data[i*4:], off)
+	}
+	return append(data, lines.Bytes()...), nil
+}
+
+// encodeFormat2Pixels writes int16 line offsets followed by lines of segments, a segment is a byte
+// with the code in 3 high bits and the length-1 in 5 low bits. Codes 0-6 are runs of the color, 7 is raw bytes.
+func encodeFormat2Pixels(rows [][]uint8) ([]byte, error) {
+	var lines bytes.Buffer
+	lineOffs := make([]int, 0, len(rows))
+	for _, row := range rows {
+		lineOffs = append(lineOffs, 2*len(rows)+lines.Len())
+		writeFormat2Segments(&lines, row)
+	}
+
+	data := make([]byte, 2*len(rows), 2*len(rows)+lines.Len())
+	for i, off := range lineOffs {
+		if off > 0x7fff {
+			return nil, errors.New("frame is too large for format 2 line offsets")
+		}
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(off))
+	}
+	return append(data, lines.Bytes()...), nil
+}
+
+// encodeFormat3Pixels writes uint16 offsets of each 32 pixels line segment followed by segments encoded as in format 2
+func encodeFormat3Pixels(rows [][]uint8) ([]byte, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	segmentsPerRow := len(rows[0]) / 32
+	offsetsSize := 2 * segmentsPerRow * len(rows)
+
+	var lines bytes.Buffer
+	data := make([]byte, offsetsSize)
+	for y, row := range rows {
+		for s := 0; s < segmentsPerRow; s++ {
+			off := offsetsSize + lines.Len()
+			if off > 0xffff {
+				return nil, errors.New("frame is too large for format 3 segment offsets")
+			}
+			binary.LittleEndian.PutUint16(data[2*(y*segmentsPerRow+s):], uint16(off))
+			writeFormat2Segments(&lines, row[s*32:(s+1)*32])
+		}
+	}
+	return append(data, lines.Bytes()...), nil
+}
+
+func writeFormat2Segments(lines *bytes.Buffer, row []uint8) {
+	for _, seg := range splitSegments(row, specialColorsCount-1, 32) {
+		if seg.raw {
+			lines.WriteByte(7<<5 | uint8(len(seg.pixels)-1))
+			lines.Write(seg.pixels)
+		} else {
+			lines.WriteByte(seg.pixels[0]<<5 | uint8(len(seg.pixels)-1))
+		}
+	}
+}
+
+type pixelsSegment struct {
+	pixels []uint8
+	raw    bool
+}
+
+// splitSegments splits a row into runs of the same color below runColors and raw segments of other colors,
+// segments are at most maxLength long
+func splitSegments(row []uint8, runColors uint8, maxLength int) []pixelsSegment {
+	var segments []pixelsSegment
+	for start := 0; start < len(row); {
+		end := start + 1
+		raw := row[start] >= runColors
+		for end < len(row) && end-start < maxLength {
+			if raw && row[end] < runColors || !raw && row[end] != row[start] {
+				break
+			}
+			end++
+		}
+		segments = append(segments, pixelsSegment{pixels: row[start:end], raw: raw})
+		start = end
+	}
+	return segments
+}
@@ -20,6 +20,12 @@ type OutFilesMeta struct {
 	BlocksMeta []DefBlockMeta `json:"blocks_meta"`
 	DefType    uint32         `json:"def_type"`
 	Format     uint32         `json:"format"`
+	// Width, Height and Palette are kept to build the def back, meta of older extractions has no them
+	Width   uint32   `json:"width,omitempty"`
+	Height  uint32   `json:"height,omitempty"`
+	Palette []string `json:"palette,omitempty"`
+	// Indexed is set when frames are written as paletted pngs of the def palette
+	Indexed bool `json:"indexed,omitempty"`
 }
 type DefBlockMeta struct {
 	Id        uint32     `json:"block_id"`
@@ -40,14 +46,42 @@ type ImageMeta struct {
 	TopMargin  int32
 }
 
+// ExtractOptions tune how def frames are written
+type ExtractOptions struct {
+	// Indexed writes frames as paletted pngs with the exact 256 colors def palette. Special colors keep
+	// their indexes instead of being replaced with transparency, so frames can be edited and built back as is.
+	Indexed bool
+	// Players are written as extra frame sets with player colors, into a directory named after the player
+	// inside the def directory
+	Players []PlayerColor
+	// PlayerPalettes are colors of Players, DefaultPlayerPalettes are used when it's nil
+	PlayerPalettes *PlayerPalettes
+	// Animation writes every block as <block id>.gif and <block id>.png apng next to block directories when it's set
+	Animation *AnimationOptions
+}
+
 func ExtractDef(defPath, outDir string) error {
+	return ExtractDefWithOptions(defPath, outDir, ExtractOptions{})
+}
+
+func ExtractDefWithOptions(defPath, outDir string, opts ExtractOptions) error {
 	defFile, err := os.Open(defPath)
 	if err != nil {
 		return fmt.Errorf("can't read def file: %w", err)
 	}
 	defer defFile.Close()
 
-	defType, _, _, defBlocksCount, err := readDefMeta(defFile)
+	defName := filepath.Base(strings.TrimSuffix(defPath, filepath.Ext(defPath)))
+	return ExtractDefReaderWithOptions(defFile, defName, outDir, opts)
+}
+
+// ExtractDefReader extracts def content read from defFile into the defName directory inside outDir
+func ExtractDefReader(defFile io.ReadSeeker, defName, outDir string) error {
+	return ExtractDefReaderWithOptions(defFile, defName, outDir, ExtractOptions{})
+}
+
+func ExtractDefReaderWithOptions(defFile io.ReadSeeker, defName, outDir string, opts ExtractOptions) error {
+	defType, width, height, defBlocksCount, err := readDefMeta(defFile)
 	if err != nil {
 		return fmt.Errorf("can't read def header: %w", err)
 	}
@@ -62,9 +96,34 @@ func ExtractDef(defPath, outDir string) error {
 		return fmt.Errorf("can't read def blocks meta: %w", err)
 	}
 
-	defName := filepath.Base(strings.TrimSuffix(defPath, filepath.Ext(defPath)))
 	defOutDir := filepath.Join(outDir, defName)
-	err = extractBlocksContent(defFile, defBlocksMeta, *palette, defOutDir, defType)
+	ofm := OutFilesMeta{
+		DefType: defType,
+		BlocksMeta: *defBlocksMeta,
+		Format: 99999,
+		Width: width,
+		Height: height,
+		Palette: paletteToHex(*palette),
+		Indexed: opts.Indexed,
+	}
+	outputs := []frameOutput{{dir: defOutDir, palette: *palette}}
+	if len(opts.Players) != 0 {
+		playerPalettes := opts.PlayerPalettes
+		if playerPalettes == nil {
+			playerPalettes = DefaultPlayerPalettes()
+		}
+		for _, player := range opts.Players {
+			playerPalette, err := playerPalettes.Palette(*palette, player)
+			if err != nil {
+				return err
+			}
+			outputs = append(outputs, frameOutput{
+				dir:     filepath.Join(defOutDir, player.String()),
+				palette: playerPalette,
+			})
+		}
+	}
+	err = extractBlocksContent(defFile, &ofm, outputs, opts.Animation)
 	if err != nil {
 		return fmt.Errorf("can't extract def blocks content: %w", err)
 	}
@@ -72,7 +131,7 @@ func ExtractDef(defPath, outDir string) error {
 	return nil
 }
 
-func readDefMeta(defFile *os.File) (defType, width, height, blocks uint32, err error) {
+func readDefMeta(defFile io.ReadSeeker) (defType, width, height, blocks uint32, err error) {
 	err = binread.ReadUint32(defFile, &defType)
 	if err != nil {
 		return 0, 0, 0, 0, fmt.Errorf("can't read def type: %w", err)
@@ -96,7 +155,7 @@ func readDefMeta(defFile *os.File) (defType, width, height, blocks uint32, err e
 	return defType, width, height, blocks, nil
 }
 
-func readDefPalette(defFile *os.File) (*color.Palette, error) {
+func readDefPalette(defFile io.ReadSeeker) (*color.Palette, error) {
 	palette := make(color.Palette, 256)
 	for i := 0; i < 256; i++ {
 		var r uint8
@@ -121,7 +180,7 @@ func readDefPalette(defFile *os.File) (*color.Palette, error) {
 	return &palette, nil
 }
 
-func readDefBlocksMeta(defFile *os.File, defBlocks uint32) (*[]DefBlockMeta, error) {
+func readDefBlocksMeta(defFile io.ReadSeeker, defBlocks uint32) (*[]DefBlockMeta, error) {
 	_, err := defFile.Seek(784, io.SeekStart)
 	if err != nil {
 		return nil, fmt.Errorf("can't seek to def blocks: %w", err)
@@ -174,25 +233,29 @@ func readDefBlocksMeta(defFile *os.File, defBlocks uint32) (*[]DefBlockMeta, err
 	return &blocks, nil
 }
 
-func extractBlocksContent(defFile *os.File, blocksMeta *[]DefBlockMeta, palette color.Palette, defOutDir string, defType uint32) error {
+// frameOutput is a directory frames are written to with their palette, the first one is the def directory
+type frameOutput struct {
+	dir     string
+	palette color.Palette
+}
+
+func extractBlocksContent(defFile io.ReadSeeker, ofm *OutFilesMeta, outputs []frameOutput, animation *AnimationOptions) error {
+	defOutDir := outputs[0].dir
 	err := resetDefOutDir(defOutDir)
 	if err != nil {
 		return err
 	}
 
-	ofm := OutFilesMeta{
-		DefType: defType,
-		BlocksMeta: *blocksMeta,
-		Format: 99999,
-	}
-
-	for _, bm := range *blocksMeta {
-		defBlockOutDir := filepath.Join(defOutDir, strconv.Itoa(int(bm.Id)))
-		err := os.Mkdir(defBlockOutDir, 0700)
-		if err != nil {
-			return fmt.Errorf("can't create def dir(%s): %w", defBlockOutDir, err)
+	for _, bm := range ofm.BlocksMeta {
+		for _, output := range outputs {
+			defBlockOutDir := filepath.Join(output.dir, strconv.Itoa(int(bm.Id)))
+			err := os.MkdirAll(defBlockOutDir, 0700)
+			if err != nil {
+				return fmt.Errorf("can't create def dir(%s): %w", defBlockOutDir, err)
+			}
 		}
 
+		block := DefBlock{Id: bm.Id}
 		var firstFullWidth, firstFullHeight uint32 = 99999, 99999
 		for _, di := range bm.DefImages {
 			_, err := defFile.Seek(int64(di.Offset), io.SeekStart)
@@ -238,28 +301,37 @@ func extractBlocksContent(defFile *os.File, blocksMeta *[]DefBlockMeta, palette
 				return errors.New(fmt.Sprintf("%s got different format than first image", di.Name))
 			}
 
-			var imgRGBA *image.RGBA
+			var pixels []uint8
 			if imgMeta.Width != 0 && imgMeta.Height != 0 {
-				pixels, err := readPixels(defFile, di, imgMeta)
+				pixels, err = readPixels(defFile, di, imgMeta)
 				if err != nil {
 					return fmt.Errorf("cant read pixels of image %s: %w", di.Name, err)
 				}
-				imgRGBA = decodePixels(pixels, palette, imgMeta)
-			} else {
-				imgRGBA = image.NewRGBA(image.Rect(0, 0, 0, 0))
 			}
 
 			srcImgName := filepath.Base(strings.TrimSuffix(di.Name, filepath.Ext(di.Name)))
-			imageDstPath := filepath.Join(defBlockOutDir, srcImgName + ".png")
-			file, err := os.Create(imageDstPath)
-			if err != nil {
-				return fmt.Errorf("can't create png file(%s): %w", imageDstPath, err)
+			for _, output := range outputs {
+				imageDstPath := filepath.Join(output.dir, strconv.Itoa(int(bm.Id)), srcImgName+".png")
+				err := writeFramePng(imageDstPath, pixels, output.palette, imgMeta, ofm.Indexed)
+				if err != nil {
+					return err
+				}
 			}
-			defer file.Close()
 
-			err = png.Encode(file, imgRGBA)
-			if err != nil {
-				return fmt.Errorf("can't encode png(%s): %w", imageDstPath, err)
+			if animation != nil {
+				img := image.NewPaletted(image.Rect(0, 0, int(imgMeta.Width), int(imgMeta.Height)), outputs[0].palette)
+				img.Pix = pixels
+				img.Rect = img.Rect.Add(image.Pt(int(imgMeta.LeftMargin), int(imgMeta.TopMargin)))
+				block.Frames = append(block.Frames, DefFrame{Name: di.Name, Offset: di.Offset, Meta: *imgMeta, Image: img})
+			}
+		}
+
+		if animation != nil {
+			for _, output := range outputs {
+				err := writeBlockAnimations(filepath.Join(output.dir, strconv.Itoa(int(bm.Id))), block.withPalette(output.palette), *animation)
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -277,7 +349,65 @@ func extractBlocksContent(defFile *os.File, blocksMeta *[]DefBlockMeta, palette
 	return  nil
 }
 
-func readImageMeta(defFile *os.File) (*ImageMeta, error) {
+func writeFramePng(imageDstPath string, pixels []uint8, palette color.Palette, imgMeta *ImageMeta, indexed bool) error {
+	var img image.Image
+	switch {
+	case len(pixels) != 0 && indexed:
+		img = decodeIndexedPixels(pixels, palette, imgMeta)
+	case len(pixels) != 0:
+		img = decodePixels(pixels, palette, imgMeta)
+	case indexed:
+		img = image.NewPaletted(image.Rect(0, 0, 0, 0), palette)
+	default:
+		img = image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	file, err := os.Create(imageDstPath)
+	if err != nil {
+		return fmt.Errorf("can't create png file(%s): %w", imageDstPath, err)
+	}
+	defer file.Close()
+
+	err = png.Encode(file, img)
+	if err != nil {
+		return fmt.Errorf("can't encode png(%s): %w", imageDstPath, err)
+	}
+	return nil
+}
+
+// writeBlockAnimations writes the block as gif and apng files at the path without extension, blocks of empty frames are skipped
+func writeBlockAnimations(path string, block DefBlock, opts AnimationOptions) error {
+	if _, err := block.animationFrames(); err != nil {
+		// blocks without anything to animate are skipped
+		if errors.Is(err, errNoFrames) || errors.Is(err, errEmptyFrames) {
+			return nil
+		}
+		return err
+	}
+	encoders := []struct {
+		ext    string
+		encode func(io.Writer, AnimationOptions) error
+	}{
+		{".gif", block.EncodeGIF},
+		{".png", block.EncodeAPNG},
+	}
+	for _, encoder := range encoders {
+		file, err := os.Create(path + encoder.ext)
+		if err != nil {
+			return fmt.Errorf("can't create animation file(%s): %w", path+encoder.ext, err)
+		}
+		err = encoder.encode(file, opts)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("can't write animation file(%s): %w", path+encoder.ext, err)
+		}
+	}
+	return nil
+}
+
+func readImageMeta(defFile io.ReadSeeker) (*ImageMeta, error) {
 	var imageMeta ImageMeta
 
 	err := binread.ReadUint32(defFile, &imageMeta.Size)
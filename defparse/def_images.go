@@ -30,6 +30,14 @@ func decodePixels(pixels []uint8, palette color.Palette, imgMeta *ImageMeta) *im
 	return imgRGBA
 }
 
+// decodeIndexedPixels draws pixels on the full frame canvas keeping palette indexes, the area around them is index 0
+func decodeIndexedPixels(pixels []uint8, palette color.Palette, imgMeta *ImageMeta) *image.Paletted {
+	frame := DefFrame{Meta: *imgMeta, Image: image.NewPaletted(image.Rect(0, 0, int(imgMeta.Width), int(imgMeta.Height)), palette)}
+	frame.Image.Pix = pixels
+	frame.Image.Rect = frame.Image.Rect.Add(image.Pt(int(imgMeta.LeftMargin), int(imgMeta.TopMargin)))
+	return frame.FullImage()
+}
+
 func replaceDefSpecialColors(img *image.RGBA, imgMeta *ImageMeta) {
 	for x := int(imgMeta.LeftMargin); x < int(imgMeta.LeftMargin)+int(imgMeta.Width); x++ {
 		for y := int(imgMeta.TopMargin); y < int(imgMeta.TopMargin)+int(imgMeta.Height); y++ {
@@ -0,0 +1,205 @@
+package defparse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/netscrn/homm3utils/internal/binread"
+)
+
+const (
+	// PlayerColorsStart is the first palette index the game swaps for player colors
+	PlayerColorsStart = 224
+	// PlayerColorShades is the number of palette indexes swapped for player colors
+	PlayerColorShades = 32
+	PlayerColorsCount = 8
+)
+
+// PlayerColor is a player in the order of PlColors.txt and PLAYERS.PAL, colors past Pink are invalid
+type PlayerColor int
+
+const (
+	Red PlayerColor = iota
+	Blue
+	Tan
+	Green
+	Orange
+	Purple
+	Teal
+	Pink
+)
+
+var playerColorNames = [PlayerColorsCount]string{"red", "blue", "tan", "green", "orange", "purple", "teal", "pink"}
+
+// AllPlayerColors lists players in the game order
+var AllPlayerColors = []PlayerColor{Red, Blue, Tan, Green, Orange, Purple, Teal, Pink}
+
+func (pc PlayerColor) String() string {
+	if pc >= 0 && int(pc) < len(playerColorNames) {
+		return playerColorNames[pc]
+	}
+	return fmt.Sprintf("player(%d)", int(pc))
+}
+
+// checkPlayer returns an error for a player out of the game players
+func checkPlayer(player PlayerColor) error {
+	if player < 0 || int(player) >= PlayerColorsCount {
+		return fmt.Errorf("unknown player color(%d)", int(player))
+	}
+	return nil
+}
+
+// ParsePlayerColor accepts a player color name ignoring case
+func ParsePlayerColor(s string) (PlayerColor, error) {
+	for i, name := range playerColorNames {
+		if strings.EqualFold(s, name) {
+			return PlayerColor(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown player color(%s)", s)
+}
+
+// PlayerPalettes are the colors put at indexes 224-255 for every player
+type PlayerPalettes [PlayerColorsCount][PlayerColorShades]color.RGBA
+
+// playerBaseColors are the main colors of players, the ones used for flags on the adventure map
+var playerBaseColors = [PlayerColorsCount]color.RGBA{
+	{R: 0xff, G: 0x00, B: 0x00, A: 255},
+	{R: 0x31, G: 0x52, B: 0xff, A: 255},
+	{R: 0x9c, G: 0x73, B: 0x52, A: 255},
+	{R: 0x42, G: 0x94, B: 0x29, A: 255},
+	{R: 0xff, G: 0x84, B: 0x00, A: 255},
+	{R: 0x8c, G: 0x29, B: 0xa5, A: 255},
+	{R: 0x09, G: 0x9c, B: 0xa5, A: 255},
+	{R: 0xc6, G: 0x7b, B: 0x8c, A: 255},
+}
+
+// DefaultPlayerPalettes approximates the game player palettes with shades from dark to light around
+// the player base color. Use ReadPlayerPalettes on PLAYERS.PAL of H3bitmap.lod for the exact game colors.
+func DefaultPlayerPalettes() *PlayerPalettes {
+	var palettes PlayerPalettes
+	for player, base := range playerBaseColors {
+		for shade := 0; shade < PlayerColorShades; shade++ {
+			palettes[player][shade] = playerShade(base, shade)
+		}
+	}
+	return &palettes
+}
+
+// playerShade darkens the base color for the first 24 shades and lightens it towards white for the rest
+func playerShade(base color.RGBA, shade int) color.RGBA {
+	const baseShade = 23
+	mix := func(from, to uint8, num, den int) uint8 {
+		return uint8(int(from) + (int(to)-int(from))*num/den)
+	}
+	if shade <= baseShade {
+		num := shade + 4
+		return color.RGBA{R: mix(0, base.R, num, baseShade+4), G: mix(0, base.G, num, baseShade+4), B: mix(0, base.B, num, baseShade+4), A: 255}
+	}
+	num := shade - baseShade
+	return color.RGBA{R: mix(base.R, 255, num, 16), G: mix(base.G, 255, num, 16), B: mix(base.B, 255, num, 16), A: 255}
+}
+
+// ReadPlayerPalettes reads a RIFF palette like PLAYERS.PAL with 32 colors per player in the game order
+func ReadPlayerPalettes(r io.Reader) (*PlayerPalettes, error) {
+	header := make([]byte, 16)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, fmt.Errorf("can't read palette header: %w", err)
+	}
+	if !bytes.Equal(header[0:4], []byte("RIFF")) || !bytes.Equal(header[8:16], []byte("PAL data")) {
+		return nil, errors.New("not a riff palette")
+	}
+	var chunkSize uint32
+	err = binread.ReadUint32(r, &chunkSize)
+	if err != nil {
+		return nil, fmt.Errorf("can't read palette chunk size: %w", err)
+	}
+	var version, colorsCount uint16
+	err = binread.ReadUint16(r, &version)
+	if err != nil {
+		return nil, fmt.Errorf("can't read palette version: %w", err)
+	}
+	err = binread.ReadUint16(r, &colorsCount)
+	if err != nil {
+		return nil, fmt.Errorf("can't read palette colors count: %w", err)
+	}
+	if int(colorsCount) < PlayerColorsCount*PlayerColorShades {
+		return nil, fmt.Errorf("palette has %d colors, %d are needed", colorsCount, PlayerColorsCount*PlayerColorShades)
+	}
+
+	var palettes PlayerPalettes
+	entry := make([]byte, 4)
+	for player := 0; player < PlayerColorsCount; player++ {
+		for shade := 0; shade < PlayerColorShades; shade++ {
+			_, err := io.ReadFull(r, entry)
+			if err != nil {
+				return nil, fmt.Errorf("can't read %s color(%d): %w", PlayerColor(player), shade, err)
+			}
+			palettes[player][shade] = color.RGBA{R: entry[0], G: entry[1], B: entry[2], A: 255}
+		}
+	}
+	return &palettes, nil
+}
+
+// Palette returns a copy of palette with player colors of the player
+func (pp *PlayerPalettes) Palette(palette color.Palette, player PlayerColor) (color.Palette, error) {
+	if err := checkPlayer(player); err != nil {
+		return nil, err
+	}
+	recolored := append(color.Palette(nil), palette...)
+	for shade, c := range pp[player] {
+		if PlayerColorsStart+shade < len(recolored) {
+			recolored[PlayerColorsStart+shade] = c
+		}
+	}
+	return recolored, nil
+}
+
+// WithPlayerColor returns a copy of the def rendered in the player color, pixels are shared with d
+func (d *Def) WithPlayerColor(palettes *PlayerPalettes, player PlayerColor) (*Def, error) {
+	if palettes == nil {
+		palettes = DefaultPlayerPalettes()
+	}
+	palette, err := palettes.Palette(d.Palette, player)
+	if err != nil {
+		return nil, err
+	}
+	recolored := *d
+	recolored.Palette = palette
+	recolored.Blocks = make([]DefBlock, 0, len(d.Blocks))
+	for _, block := range d.Blocks {
+		recolored.Blocks = append(recolored.Blocks, block.withPalette(recolored.Palette))
+	}
+	return &recolored, nil
+}
+
+// withPalette returns a copy of the block with frames of the palette, pixels are shared with db
+func (db DefBlock) withPalette(palette color.Palette) DefBlock {
+	frames := make([]DefFrame, 0, len(db.Frames))
+	for _, frame := range db.Frames {
+		img := *frame.Image
+		img.Palette = palette
+		frame.Image = &img
+		frames = append(frames, frame)
+	}
+	return DefBlock{Id: db.Id, Frames: frames}
+}
+
+// PlayerImage returns the full frame in the player color, special colors are replaced the same way RGBA does
+func (df *DefFrame) PlayerImage(palettes *PlayerPalettes, player PlayerColor) (*image.RGBA, error) {
+	if palettes == nil {
+		palettes = DefaultPlayerPalettes()
+	}
+	palette, err := palettes.Palette(df.Image.Palette, player)
+	if err != nil {
+		return nil, err
+	}
+	meta := df.Meta
+	return decodePixels(df.Image.Pix, palette, &meta), nil
+}
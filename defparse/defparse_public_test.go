@@ -0,0 +1,481 @@
+package defparse_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netscrn/homm3utils/defparse"
+)
+
+// defs of the lodparse test archive are used, defparse has no own test data
+var testDefsDir = filepath.Join("..", "lodparse", "testdata", "HotA_lng_files")
+
+func decodeTestDef(t *testing.T, name string) *defparse.Def {
+	t.Helper()
+	defFile, err := os.Open(filepath.Join(testDefsDir, name))
+	if err != nil {
+		t.Fatalf("Can't open def: %v", err)
+	}
+	defer defFile.Close()
+	def, err := defparse.DecodeDef(defFile)
+	if err != nil {
+		t.Fatalf("Can't decode def(%s): %v", name, err)
+	}
+	return def
+}
+
+func TestDecodeDef(t *testing.T) {
+	dirContent, err := os.ReadDir(testDefsDir)
+	if err != nil {
+		t.Fatalf("Can't read test defs dir: %v", err)
+	}
+	decoded := 0
+	for _, entry := range dirContent {
+		if !strings.EqualFold(filepath.Ext(entry.Name()), ".def") {
+			continue
+		}
+		def := decodeTestDef(t, entry.Name())
+		if len(def.Palette) != 256 || len(def.Blocks) == 0 {
+			t.Errorf("Def(%s) has %d palette colors and %d blocks", entry.Name(), len(def.Palette), len(def.Blocks))
+		}
+		for _, block := range def.Blocks {
+			for _, frame := range block.Frames {
+				bounds := frame.Image.Bounds()
+				if bounds.Dx() != int(frame.Meta.Width) || bounds.Dy() != int(frame.Meta.Height) {
+					t.Errorf("Frame(%s) of def(%s) is %v, meta tells %dx%d", frame.Name, entry.Name(), bounds, frame.Meta.Width, frame.Meta.Height)
+				}
+			}
+		}
+		decoded++
+	}
+	if decoded == 0 {
+		t.Fatal("No test defs found")
+	}
+}
+
+func TestDecodeDefMatchesExtractDef(t *testing.T) {
+	outDir := t.TempDir()
+	err := defparse.ExtractDef(filepath.Join(testDefsDir, "AVArnd1.def"), outDir)
+	if err != nil {
+		t.Fatalf("Can't extract def: %v", err)
+	}
+
+	def := decodeTestDef(t, "AVArnd1.def")
+	block := def.Blocks[0]
+	frame := block.Frames[0]
+	pngFile, err := os.Open(filepath.Join(outDir, "AVArnd1", strconv.Itoa(int(block.Id)), strings.TrimSuffix(frame.Name, filepath.Ext(frame.Name))+".png"))
+	if err != nil {
+		t.Fatalf("Can't open extracted frame: %v", err)
+	}
+	defer pngFile.Close()
+	extracted, err := png.Decode(pngFile)
+	if err != nil {
+		t.Fatalf("Can't decode extracted frame: %v", err)
+	}
+
+	decoded := frame.RGBA()
+	if decoded.Bounds() != extracted.Bounds() {
+		t.Fatalf("Decoded frame bounds %v differ from extracted %v", decoded.Bounds(), extracted.Bounds())
+	}
+	for y := 0; y < decoded.Bounds().Dy(); y++ {
+		for x := 0; x < decoded.Bounds().Dx(); x++ {
+			dr, dg, db, da := decoded.At(x, y).RGBA()
+			er, eg, eb, ea := extracted.At(x, y).RGBA()
+			if dr != er || dg != eg || db != eb || da != ea {
+				t.Fatalf("Decoded frame differs from extracted at (%d, %d)", x, y)
+			}
+		}
+	}
+
+	full := frame.FullImage()
+	if full.Bounds().Dx() != int(frame.Meta.FullWight) || full.Bounds().Dy() != int(frame.Meta.FullHeight) {
+		t.Errorf("Wrong full image bounds: %v", full.Bounds())
+	}
+}
+
+func TestEncodeDefRoundTrip(t *testing.T) {
+	dirContent, err := os.ReadDir(testDefsDir)
+	if err != nil {
+		t.Fatalf("Can't read test defs dir: %v", err)
+	}
+	for _, entry := range dirContent {
+		if !strings.EqualFold(filepath.Ext(entry.Name()), ".def") {
+			continue
+		}
+		def := decodeTestDef(t, entry.Name())
+		for format := uint32(0); format <= 3; format++ {
+			if format == 3 && !framesFitFormat3(def) {
+				continue
+			}
+			var encoded bytes.Buffer
+			err := defparse.EncodeDef(&encoded, def, format)
+			if err != nil {
+				t.Fatalf("Can't encode def(%s) in format %d: %v", entry.Name(), format, err)
+			}
+			reDecoded, err := defparse.DecodeDef(bytes.NewReader(encoded.Bytes()))
+			if err != nil {
+				t.Fatalf("Can't decode def(%s) encoded in format %d: %v", entry.Name(), format, err)
+			}
+			assertSameFrames(t, def, reDecoded, fmt.Sprintf("%s in format %d", entry.Name(), format))
+		}
+	}
+}
+
+func framesFitFormat3(def *defparse.Def) bool {
+	for _, block := range def.Blocks {
+		for _, frame := range block.Frames {
+			if frame.Image.Rect.Dx()%32 != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func assertSameFrames(t *testing.T, expected, actual *defparse.Def, what string) {
+	t.Helper()
+	if len(actual.Blocks) != len(expected.Blocks) || actual.Type != expected.Type {
+		t.Fatalf("Def(%s) has %d blocks of type %d, expected %d of type %d", what, len(actual.Blocks), actual.Type, len(expected.Blocks), expected.Type)
+	}
+	for bi, block := range expected.Blocks {
+		actualBlock := actual.Blocks[bi]
+		if actualBlock.Id != block.Id || len(actualBlock.Frames) != len(block.Frames) {
+			t.Fatalf("Block [%d] of def(%s) differs", bi, what)
+		}
+		for fi, frame := range block.Frames {
+			actualFrame := actualBlock.Frames[fi]
+			if actualFrame.Name != frame.Name {
+				t.Errorf("Frame [%d][%d] of def(%s) is named %s, expected %s", bi, fi, what, actualFrame.Name, frame.Name)
+			}
+			if !bytes.Equal(actualFrame.FullImage().Pix, frame.FullImage().Pix) {
+				t.Errorf("Frame(%s) of def(%s) differs", frame.Name, what)
+			}
+		}
+	}
+}
+
+func TestEncodeDefRepeatedFrames(t *testing.T) {
+	def := decodeTestDef(t, "AVArnd1.def")
+	frame := def.Blocks[0].Frames[0]
+	repeated := frame
+	repeated.Image = &image.Paletted{Pix: append([]uint8(nil), frame.Image.Pix...), Stride: frame.Image.Stride, Rect: frame.Image.Rect, Palette: frame.Image.Palette}
+	def.Blocks = append(def.Blocks, defparse.DefBlock{Id: 1, Frames: []defparse.DefFrame{repeated}})
+
+	var encoded bytes.Buffer
+	err := defparse.EncodeDef(&encoded, def, 0)
+	if err != nil {
+		t.Fatalf("Can't encode def with equal repeated frames: %v", err)
+	}
+
+	repeated.Image.Pix[0]++
+	err = defparse.EncodeDef(&encoded, def, 0)
+	if err == nil {
+		t.Error("Expected error encoding def with different frames of the same name")
+	}
+}
+
+func TestBuildDefWithoutFrames(t *testing.T) {
+	defOutDir := t.TempDir()
+	meta := []byte(`{"blocks_meta": [{"block_id": 0, "images": []}], "def_type": 66, "format": 99999}`)
+	err := os.WriteFile(filepath.Join(defOutDir, "meta.json"), meta, 0600)
+	if err != nil {
+		t.Fatalf("Can't write def meta: %v", err)
+	}
+	builtPath := filepath.Join(defOutDir, "empty.def")
+	err = defparse.BuildDef(defOutDir, builtPath)
+	if err != nil {
+		t.Fatalf("Can't build def without frames: %v", err)
+	}
+	builtFile, err := os.Open(builtPath)
+	if err != nil {
+		t.Fatalf("Can't open built def: %v", err)
+	}
+	defer builtFile.Close()
+	built, err := defparse.DecodeDef(builtFile)
+	if err != nil {
+		t.Fatalf("Can't decode built def: %v", err)
+	}
+	if built.Type != 66 || len(built.Blocks) != 1 || len(built.Blocks[0].Frames) != 0 {
+		t.Errorf("Wrong def built without frames: type %d, %d blocks", built.Type, len(built.Blocks))
+	}
+}
+
+func TestBuildDefFromExtracted(t *testing.T) {
+	outDir := t.TempDir()
+	for _, name := range []string{"AVArnd1.def", "GTMULTI.def"} {
+		err := defparse.ExtractDef(filepath.Join(testDefsDir, name), outDir)
+		if err != nil {
+			t.Fatalf("Can't extract def: %v", err)
+		}
+		builtPath := filepath.Join(outDir, "built_"+name)
+		err = defparse.BuildDef(filepath.Join(outDir, strings.TrimSuffix(name, filepath.Ext(name))), builtPath)
+		if err != nil {
+			t.Fatalf("Can't build def(%s): %v", name, err)
+		}
+
+		builtFile, err := os.Open(builtPath)
+		if err != nil {
+			t.Fatalf("Can't open built def: %v", err)
+		}
+		built, err := defparse.DecodeDef(builtFile)
+		builtFile.Close()
+		if err != nil {
+			t.Fatalf("Can't decode built def(%s): %v", name, err)
+		}
+
+		original := decodeTestDef(t, name)
+		if !reflect.DeepEqual(built.Palette, original.Palette) {
+			t.Errorf("Built def(%s) palette differs", name)
+		}
+		for bi, block := range original.Blocks {
+			for fi, frame := range block.Frames {
+				expected, actual := frame.RGBA(), built.Blocks[bi].Frames[fi].RGBA()
+				if !reflect.DeepEqual(expected.Bounds(), actual.Bounds()) || !bytes.Equal(visiblePix(expected), visiblePix(actual)) {
+					t.Errorf("Frame(%s) of built def(%s) differs", frame.Name, name)
+				}
+			}
+		}
+	}
+}
+
+// visiblePix drops colors of fully transparent pixels
+func visiblePix(img *image.RGBA) []uint8 {
+	pix := append([]uint8(nil), img.Pix...)
+	for i := 0; i < len(pix); i += 4 {
+		if pix[i+3] == 0 {
+			pix[i], pix[i+1], pix[i+2] = 0, 0, 0
+		}
+	}
+	return pix
+}
+
+func TestExtractDefIndexed(t *testing.T) {
+	outDir := t.TempDir()
+	for _, name := range []string{"AVArnd1.def", "GTMULTI.def"} {
+		err := defparse.ExtractDefWithOptions(filepath.Join(testDefsDir, name), outDir, defparse.ExtractOptions{Indexed: true})
+		if err != nil {
+			t.Fatalf("Can't extract def: %v", err)
+		}
+		defOutDir := filepath.Join(outDir, strings.TrimSuffix(name, filepath.Ext(name)))
+
+		original := decodeTestDef(t, name)
+		for _, block := range original.Blocks {
+			for _, frame := range block.Frames {
+				pngPath := filepath.Join(defOutDir, strconv.Itoa(int(block.Id)), strings.TrimSuffix(frame.Name, filepath.Ext(frame.Name))+".png")
+				pngFile, err := os.Open(pngPath)
+				if err != nil {
+					t.Fatalf("Can't open extracted frame: %v", err)
+				}
+				img, err := png.Decode(pngFile)
+				pngFile.Close()
+				if err != nil {
+					t.Fatalf("Can't decode extracted frame(%s): %v", pngPath, err)
+				}
+				paletted, ok := img.(*image.Paletted)
+				if !ok {
+					t.Fatalf("Frame(%s) of def(%s) is %T, expected paletted", frame.Name, name, img)
+				}
+				if !reflect.DeepEqual(paletted.Palette, original.Palette) {
+					t.Errorf("Frame(%s) of def(%s) palette differs", frame.Name, name)
+				}
+				if !bytes.Equal(paletted.Pix, frame.FullImage().Pix) {
+					t.Errorf("Frame(%s) of def(%s) indexes differ", frame.Name, name)
+				}
+			}
+		}
+
+		built, _, err := defparse.LoadExtractedDef(defOutDir)
+		if err != nil {
+			t.Fatalf("Can't load extracted def(%s): %v", name, err)
+		}
+		assertSameFrames(t, original, built, name)
+	}
+}
+
+func TestPlayerColors(t *testing.T) {
+	var palettes defparse.PlayerPalettes
+	palFile := bytes.NewBufferString("RIFF\x00\x00\x00\x00PAL data\x00\x00\x00\x00\x00\x03\x00\x01")
+	for player := 0; player < defparse.PlayerColorsCount; player++ {
+		for shade := 0; shade < defparse.PlayerColorShades; shade++ {
+			c := color.RGBA{R: uint8(player), G: uint8(shade), B: 200, A: 255}
+			palettes[player][shade] = c
+			palFile.Write([]byte{c.R, c.G, c.B, 0})
+		}
+	}
+	readPalettes, err := defparse.ReadPlayerPalettes(palFile)
+	if err != nil {
+		t.Fatalf("Can't read player palettes: %v", err)
+	}
+	if *readPalettes != palettes {
+		t.Fatal("Read player palettes differ")
+	}
+
+	original := decodeTestDef(t, "AVArnd1.def")
+	blue, err := original.WithPlayerColor(&palettes, defparse.Blue)
+	if err != nil {
+		t.Fatalf("Can't recolor def: %v", err)
+	}
+	for i, c := range blue.Palette {
+		expected := original.Palette[i]
+		if i >= defparse.PlayerColorsStart {
+			expected = palettes[defparse.Blue][i-defparse.PlayerColorsStart]
+		}
+		if c != expected {
+			t.Fatalf("Blue palette color [%d] is %v, expected %v", i, c, expected)
+		}
+	}
+	frame := blue.Blocks[0].Frames[0]
+	playerImage, err := original.Blocks[0].Frames[0].PlayerImage(&palettes, defparse.Blue)
+	if err != nil {
+		t.Fatalf("Can't get player image: %v", err)
+	}
+	if !bytes.Equal(frame.RGBA().Pix, playerImage.Pix) {
+		t.Error("Recolored def frame differs from player image")
+	}
+	for _, player := range []defparse.PlayerColor{-1, defparse.PlayerColorsCount} {
+		if _, err := original.WithPlayerColor(&palettes, player); err == nil {
+			t.Errorf("Expected error recoloring def for player(%d)", player)
+		}
+		if _, err := original.Blocks[0].Frames[0].PlayerImage(nil, player); err == nil {
+			t.Errorf("Expected error getting image of player(%d)", player)
+		}
+	}
+
+	outDir := t.TempDir()
+	opts := defparse.ExtractOptions{Indexed: true, Players: []defparse.PlayerColor{defparse.Red, defparse.Blue}, PlayerPalettes: &palettes}
+	err = defparse.ExtractDefWithOptions(filepath.Join(testDefsDir, "AVArnd1.def"), outDir, opts)
+	if err != nil {
+		t.Fatalf("Can't extract def: %v", err)
+	}
+	pngPath := filepath.Join(outDir, "AVArnd1", "blue", strconv.Itoa(int(blue.Blocks[0].Id)), strings.TrimSuffix(frame.Name, filepath.Ext(frame.Name))+".png")
+	pngFile, err := os.Open(pngPath)
+	if err != nil {
+		t.Fatalf("Can't open player frame: %v", err)
+	}
+	img, err := png.Decode(pngFile)
+	pngFile.Close()
+	if err != nil {
+		t.Fatalf("Can't decode player frame: %v", err)
+	}
+	if paletted, ok := img.(*image.Paletted); !ok || !reflect.DeepEqual(paletted.Palette, blue.Palette) {
+		t.Error("Blue frame isn't written with the blue palette")
+	}
+}
+
+func TestBlockAnimations(t *testing.T) {
+	def := decodeTestDef(t, "AVArnd1.def")
+	block := def.Blocks[0]
+	opts := defparse.AnimationOptions{Delay: 150 * time.Millisecond}
+
+	var gifData bytes.Buffer
+	err := block.EncodeGIF(&gifData, opts)
+	if err != nil {
+		t.Fatalf("Can't encode gif: %v", err)
+	}
+	anim, err := gif.DecodeAll(&gifData)
+	if err != nil {
+		t.Fatalf("Can't decode gif: %v", err)
+	}
+	if len(anim.Image) != len(block.Frames) {
+		t.Fatalf("Gif has %d frames, expected %d", len(anim.Image), len(block.Frames))
+	}
+	for i, frame := range anim.Image {
+		if frame.Rect != image.Rect(0, 0, anim.Config.Width, anim.Config.Height) || anim.Delay[i] != 15 {
+			t.Errorf("Gif frame [%d] is %v with delay %d", i, frame.Rect, anim.Delay[i])
+		}
+		if _, _, _, a := frame.At(0, 0).RGBA(); a != 0 && block.Frames[i].FullImage().ColorIndexAt(0, 0) == 0 {
+			t.Errorf("Gif frame [%d] background isn't transparent", i)
+		}
+	}
+
+	gifData.Reset()
+	err = block.EncodeGIF(&gifData, defparse.AnimationOptions{Delay: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Can't encode gif: %v", err)
+	}
+	anim, err = gif.DecodeAll(&gifData)
+	if err != nil {
+		t.Fatalf("Can't decode gif: %v", err)
+	}
+	if anim.Delay[0] != 1 {
+		t.Errorf("Gif delay under 10ms is %d, expected 1", anim.Delay[0])
+	}
+
+	var apngData bytes.Buffer
+	err = block.EncodeAPNG(&apngData, opts)
+	if err != nil {
+		t.Fatalf("Can't encode apng: %v", err)
+	}
+	frames := splitAPNG(t, apngData.Bytes())
+	if len(frames) != len(block.Frames) {
+		t.Fatalf("Apng has %d frames, expected %d", len(frames), len(block.Frames))
+	}
+	for i, frame := range frames {
+		img, err := png.Decode(bytes.NewReader(frame))
+		if err != nil {
+			t.Fatalf("Can't decode apng frame [%d]: %v", i, err)
+		}
+		expected := block.Frames[i].RGBA()
+		actual := image.NewRGBA(expected.Rect)
+		draw.Draw(actual, actual.Rect, img, image.Point{}, draw.Src)
+		if !bytes.Equal(visiblePix(actual), visiblePix(expected)) {
+			t.Errorf("Apng frame [%d] differs from the frame", i)
+		}
+	}
+}
+
+// splitAPNG rebuilds every apng frame as a separate png of the apng headers and frame data
+func splitAPNG(t *testing.T, apng []byte) [][]byte {
+	t.Helper()
+	var headers []byte
+	var frames [][]byte
+	var sequence uint32
+	rest := apng[8:]
+	for len(rest) != 0 {
+		length := binary.BigEndian.Uint32(rest[0:4])
+		kind, data, chunk := string(rest[4:8]), rest[8:8+length], rest[:12+length]
+		rest = rest[12+length:]
+		switch kind {
+		case "IHDR", "PLTE", "tRNS":
+			headers = append(headers, chunk...)
+		case "fcTL":
+			if binary.BigEndian.Uint32(data[0:4]) != sequence || binary.BigEndian.Uint16(data[20:22]) != 150 {
+				t.Fatalf("Wrong fcTL of frame %d", len(frames))
+			}
+			sequence++
+			frames = append(frames, nil)
+		case "IDAT":
+			frames[len(frames)-1] = append(frames[len(frames)-1], chunk...)
+		case "fdAT":
+			if binary.BigEndian.Uint32(data[0:4]) != sequence {
+				t.Fatalf("Wrong fdAT sequence of frame %d", len(frames))
+			}
+			sequence++
+			idat := make([]byte, 8, 8+len(data))
+			binary.BigEndian.PutUint32(idat[0:4], length-4)
+			copy(idat[4:8], "IDAT")
+			idat = append(idat, data[4:]...)
+			crc := crc32.ChecksumIEEE(idat[4:])
+			idat = append(idat, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+			frames[len(frames)-1] = append(frames[len(frames)-1], idat...)
+		}
+	}
+	for i, frame := range frames {
+		frames[i] = append(append(append([]byte("\x89PNG\r\n\x1a\n"), headers...), frame...), 0, 0, 0, 0, 'I', 'E', 'N', 'D', 0xae, 0x42, 0x60, 0x82)
+	}
+	return frames
+}
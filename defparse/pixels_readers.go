@@ -4,12 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/netscrn/homm3utils/internal/binread"
 )
 
-func readPixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
+func readPixels(defFile io.ReadSeeker, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
 	switch imgMeta.Format {
 	case 0:
 		return readFormat0Pixels(defFile, di, imgMeta)
@@ -24,16 +23,16 @@ func readPixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uint8, err
 	}
 }
 
-func readFormat0Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
+func readFormat0Pixels(defFile io.ReadSeeker, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
 	pixels := make([]uint8, imgMeta.Width*imgMeta.Height)
-	_, err := defFile.Read(pixels)
+	_, err := io.ReadFull(defFile, pixels)
 	if err != nil {
 		return nil, fmt.Errorf("can't read image(%s) format0 pixels: %w", di.Name, err)
 	}
 	return pixels, nil
 }
 
-func readFormat1Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
+func readFormat1Pixels(defFile io.ReadSeeker, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
 	pixels := make([]uint8, 0, imgMeta.Width * imgMeta.Height)
 
 	lineOffs := make([]uint32, imgMeta.Height)
@@ -49,6 +48,7 @@ func readFormat1Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uin
 			return nil, errors.New(fmt.Sprintf("can't seek image(%s) lineoffset(%d)", di.Name, lineOff))
 		}
 
+		rowStart := len(pixels)
 		var totalRowLength uint32
 		for ;totalRowLength < imgMeta.Width; {
 			var code uint8
@@ -56,33 +56,34 @@ func readFormat1Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uin
 			if err != nil {
 				return nil, fmt.Errorf("cant read row code: %w", err)
 			}
-			var length uint8
-			err = binread.ReadUint8(defFile, &length)
+			var lengthByte uint8
+			err = binread.ReadUint8(defFile, &lengthByte)
 			if err != nil {
 				return nil, fmt.Errorf("cant read row length: %w", err)
 			}
-			length++
+			length := int(lengthByte) + 1 // 256 long segments don't fit uint8
 
 			if code == 0xff { // plain bytes
 				b := make([]byte, length)
-				_, err = defFile.Read(b)
+				_, err = io.ReadFull(defFile, b)
 				if err != nil {
 					return nil, fmt.Errorf("cant read row code: %w", err)
 				}
 				pixels = append(pixels, b...)
 			} else { // RLE
-				for i := 0; i < int(length); i++ {
+				for i := 0; i < length; i++ {
 					pixels = append(pixels, code)
 				}
 			}
 			totalRowLength += uint32(length)
 		}
+		pixels = clipRow(pixels, rowStart, imgMeta.Width)
 	}
 
 	return pixels, nil
 }
 
-func readFormat2Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
+func readFormat2Pixels(defFile io.ReadSeeker, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
 	pixels := make([]uint8, 0, imgMeta.Width * imgMeta.Height)
 
 	lineOffs := make([]int16, imgMeta.Height)
@@ -99,6 +100,7 @@ func readFormat2Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uin
 			return nil, err
 		}
 
+		rowStart := len(pixels)
 		var totalBlockLength uint32
 		for ;totalBlockLength < imgMeta.Width; {
 			var segment uint8
@@ -111,7 +113,7 @@ func readFormat2Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uin
 
 			if code == 7 { // plain bytes
 				b := make([]byte, length)
-				_, err = defFile.Read(b)
+				_, err = io.ReadFull(defFile, b)
 				if err != nil {
 					return nil, fmt.Errorf("cant read row code: %w", err)
 				}
@@ -123,12 +125,13 @@ func readFormat2Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uin
 			}
 			totalBlockLength += uint32(length)
 		}
+		pixels = clipRow(pixels, rowStart, imgMeta.Width)
 	}
 
 	return pixels, nil
 }
 
-func readFormat3Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
+func readFormat3Pixels(defFile io.ReadSeeker, di DefImage, imgMeta *ImageMeta) ([]uint8, error) {
 	pixels := make([]uint8, 0, imgMeta.Width * imgMeta.Height)
 
 	lineOffs := make([][]uint16, imgMeta.Height)
@@ -149,6 +152,7 @@ func readFormat3Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uin
 			if err != nil {
 				return nil, err
 			}
+			segmentStart := len(pixels)
 			var totalBlockLength uint32
 			for ;totalBlockLength < 32; {
 				var segment uint8
@@ -161,7 +165,7 @@ func readFormat3Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uin
 
 				if code == 7 { // plain bytes
 					b := make([]byte, length)
-					_, err = defFile.Read(b)
+					_, err = io.ReadFull(defFile, b)
 					if err != nil {
 						return nil, fmt.Errorf("cant read row code: %w", err)
 					}
@@ -173,7 +177,17 @@ func readFormat3Pixels(defFile *os.File, di DefImage, imgMeta *ImageMeta) ([]uin
 				}
 				totalBlockLength += uint32(length)
 			}
+			pixels = clipRow(pixels, segmentStart, 32)
 		}
 	}
 	return pixels, nil
+}
+
+// clipRow drops pixels of the last run that overflow the row, the game starts each row at its own offset
+// and doesn't draw them
+func clipRow(pixels []uint8, rowStart int, width uint32) []uint8 {
+	if len(pixels) > rowStart+int(width) {
+		return pixels[:rowStart+int(width)]
+	}
+	return pixels
 }
\ No newline at end of file
@@ -27,7 +27,7 @@ func ReadInt32(r io.Reader, n *int32) error {
 
 func ReadAvailableChars(r io.Reader, charsCount int) (string, error) {
 	nameBuf := make([]byte, charsCount)
-	_, err := r.Read(nameBuf)
+	_, err := io.ReadFull(r, nameBuf)
 	if err != nil {
 		return "", err
 	}
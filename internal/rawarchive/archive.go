@@ -0,0 +1,163 @@
+// Package rawarchive holds the code shared by archives storing files one after another without compression,
+// like snd and vid archives. Their tables differ, so parsing stays in sndparse and vidparse.
+package rawarchive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errNoSuchFile = errors.New("no such file in archive")
+
+type archiveFileIndex map[string]int
+
+// Archive describes an archive of uncompressed files, kind names the archive format in errors, like "snd"
+type Archive struct {
+	ArchiveFilePath string `json:"file_path"`
+	NumberOfFiles   uint32 `json:"number_of_files"`
+	Files           []File `json:"files"`
+
+	kind          string
+	filesIndexes  archiveFileIndex
+	foldedIndexes archiveFileIndex
+	reader        io.ReaderAt
+}
+
+type File struct {
+	Name   string `json:"name"`
+	Offset uint32 `json:"offset"`
+	Size   uint32 `json:"size"`
+}
+
+// New returns an archive of files, reader is used to read their content.
+// When reader is nil the archive is opened by its path on every read.
+func New(kind, pathToArchive string, files []File, reader io.ReaderAt) Archive {
+	archive := Archive{
+		ArchiveFilePath: pathToArchive,
+		NumberOfFiles:   uint32(len(files)),
+		Files:           files,
+		kind:            kind,
+		reader:          reader,
+	}
+	archive.indexFiles()
+	return archive
+}
+
+// LoadJson reads archive meta written by WriteJsonFile
+func LoadJson(kind, pathToJson string) (Archive, error) {
+	jsonFile, err := os.Open(pathToJson)
+	if err != nil {
+		return Archive{}, err
+	}
+	defer jsonFile.Close()
+
+	archive := Archive{kind: kind}
+	err = json.NewDecoder(jsonFile).Decode(&archive)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	return archive, nil
+}
+
+// ValidName tells if a file name read from an archive is safe to be used as a file name in the extraction directory,
+// it has to be a single non empty path element with no slashes or backslashes
+func ValidName(name string) bool {
+	return fs.ValidPath(name) && name != "." && !strings.ContainsAny(name, "/\\")
+}
+
+func (a *Archive) kindName() string {
+	return kindName(a.kind)
+}
+
+// kindName is used in errors, archives created without New have no kind
+func kindName(kind string) string {
+	if kind == "" {
+		return "archive"
+	}
+	return kind
+}
+
+// openReader returns the reader the meta was parsed from or opens the archive by its path
+func (a *Archive) openReader() (io.ReaderAt, io.Closer, error) {
+	if a.reader != nil {
+		return a.reader, nopCloser{}, nil
+	}
+	archiveFile, err := os.Open(a.ArchiveFilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't open %s archive(%s): %w", a.kindName(), a.ArchiveFilePath, err)
+	}
+	return archiveFile, archiveFile, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+func (a *Archive) indexFiles() {
+	a.filesIndexes = make(archiveFileIndex, len(a.Files))
+	a.foldedIndexes = make(archiveFileIndex, len(a.Files))
+	for i, file := range a.Files {
+		a.filesIndexes[file.Name] = i
+		// the first file wins when names collide in different case
+		folded := foldName(file.Name)
+		if _, ok := a.foldedIndexes[folded]; !ok {
+			a.foldedIndexes[folded] = i
+		}
+	}
+}
+
+func foldName(name string) string {
+	return strings.ToLower(name)
+}
+
+// GetFile looks a file up by its name ignoring case, the same way the game resolves resources.
+// A file with exactly matching name is preferred over files colliding in different case.
+func (a *Archive) GetFile(name string) (File, error) {
+	if a.filesIndexes == nil {
+		a.indexFiles()
+	}
+
+	fi, ok := a.filesIndexes[name]
+	if !ok {
+		fi, ok = a.foldedIndexes[foldName(name)]
+	}
+	if !ok {
+		return File{}, errNoSuchFile
+	}
+
+	if fi >= len(a.Files) {
+		return File{}, errors.New("corrupted index")
+	}
+	return a.Files[fi], nil
+}
+
+// Filter returns files for which match returns true, keeping the archive order
+func (a *Archive) Filter(match func(File) bool) []File {
+	var files []File
+	for _, file := range a.Files {
+		if match(file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (a *Archive) ToJSON() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+func (a *Archive) WriteJsonFile(jsonDstDir, filename string) error {
+	f, err := os.Create(filepath.Join(jsonDstDir, filename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(a)
+}
@@ -0,0 +1,180 @@
+package rawarchive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const defaultConcurrencyLevel = 4
+
+// ExtractOptions configures Extract
+type ExtractOptions struct {
+	// ConcurrencyLevel is the number of goroutines extracting files, 0 means default level
+	ConcurrencyLevel int
+	// ContinueOnError keeps extracting remaining files after a file fails
+	ContinueOnError bool
+	// Progress is called after each extracted file, calls are never concurrent
+	Progress func(ExtractProgress)
+	// Filter selects files to extract, all files are extracted when it's nil
+	Filter func(File) bool
+}
+
+// ExtractProgress is reported to ExtractOptions.Progress after each extracted file
+type ExtractProgress struct {
+	File         File
+	FilesDone    int
+	FilesTotal   int
+	BytesWritten int64
+}
+
+// ExtractFileError is an error of a single file extraction
+type ExtractFileError struct {
+	File File
+	Err  error
+
+	kind string
+}
+
+func (efe *ExtractFileError) Error() string {
+	return fmt.Sprintf("can't extract %s file(%s): %v", kindName(efe.kind), efe.File.Name, efe.Err)
+}
+
+func (efe *ExtractFileError) Unwrap() error {
+	return efe.Err
+}
+
+// ExtractErrors aggregates errors of all files that failed during extraction
+type ExtractErrors []*ExtractFileError
+
+func (ee ExtractErrors) Error() string {
+	msgs := make([]string, 0, len(ee))
+	kind := ""
+	for _, e := range ee {
+		msgs = append(msgs, e.Error())
+		kind = e.kind
+	}
+	return fmt.Sprintf("%d %s files failed: %s", len(ee), kindName(kind), strings.Join(msgs, "; "))
+}
+
+// Extract extracts archive files into dstDir.
+// Failed files are returned as ExtractErrors, ctx.Err() is returned when extraction was cancelled without failures.
+func Extract(ctx context.Context, archive *Archive, dstDir string, opts ExtractOptions) error {
+	files := archive.Files
+	if opts.Filter != nil {
+		files = archive.Filter(opts.Filter)
+		if len(files) == 0 {
+			return nil
+		}
+	}
+	concurrencyLevel := opts.ConcurrencyLevel
+	if concurrencyLevel == 0 {
+		concurrencyLevel = defaultConcurrencyLevel
+	}
+	if concurrencyLevel > len(files) {
+		concurrencyLevel = len(files)
+		if concurrencyLevel == 0 {
+			return fmt.Errorf("zero concurrency level due to empty %s archive", archive.kindName())
+		}
+	}
+
+	archiveReader, archiveCloser, err := archive.openReader()
+	if err != nil {
+		return err
+	}
+	defer archiveCloser.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		errs     ExtractErrors
+		progress = ExtractProgress{FilesTotal: len(files)}
+	)
+	report := func(file File, written int64, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, &ExtractFileError{File: file, Err: err, kind: archive.kind})
+			if !opts.ContinueOnError {
+				cancel()
+			}
+			return
+		}
+		progress.File = file
+		progress.FilesDone++
+		progress.BytesWritten += written
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(concurrencyLevel)
+
+	batchSize := len(files) / concurrencyLevel
+	remainingFiles := len(files) % concurrencyLevel
+	for l := 1; l <= concurrencyLevel; l++ {
+		start := batchSize * (l - 1)
+		end := batchSize * l
+		if (l == concurrencyLevel) && (remainingFiles != 0) {
+			end += remainingFiles
+		}
+
+		go func() {
+			defer wg.Done()
+			for _, file := range files[start:end] {
+				if ctx.Err() != nil {
+					return
+				}
+				written, err := writeFile(file, openFileAt(archiveReader, file), dstDir)
+				report(file, written, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) != 0 {
+		return errs
+	}
+	// cancel is only called internally on failure, so a cancelled ctx here comes from the caller
+	return ctx.Err()
+}
+
+// ExtractFile copies a single file from the archive read by archiveReader into dstDir
+func ExtractFile(file File, archiveReader io.ReaderAt, dstDir string) error {
+	_, err := writeFile(file, openFileAt(archiveReader, file), dstDir)
+	return err
+}
+
+// writeFile removes the written file when content can't be copied completely.
+// Names are checked by parsers, they are checked again for meta loaded from json.
+func writeFile(fileMeta File, content io.Reader, dstDir string) (int64, error) {
+	if !ValidName(fileMeta.Name) {
+		return 0, fmt.Errorf("invalid file name(%q)", fileMeta.Name)
+	}
+	dstPath := filepath.Join(dstDir, fileMeta.Name)
+	file, err := os.Create(dstPath)
+	if err != nil {
+		return 0, fmt.Errorf("can't create file: %w", err)
+	}
+	written, err := io.Copy(file, content)
+	if err == nil && written != int64(fileMeta.Size) {
+		err = fmt.Errorf("content is %d bytes shorter than its size: %w", int64(fileMeta.Size)-written, io.ErrUnexpectedEOF)
+	}
+	if err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
+	}
+	if err != nil {
+		os.Remove(dstPath)
+		return written, fmt.Errorf("can't write file: %w", err)
+	}
+	return written, nil
+}
@@ -0,0 +1,56 @@
+package rawarchive
+
+import (
+	"fmt"
+	"io"
+)
+
+// Open returns a reader of the named file content
+func (a *Archive) Open(name string) (io.ReadCloser, error) {
+	file, err := a.GetFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't get %s file(%s): %w", a.kindName(), name, err)
+	}
+	return a.openFile(file)
+}
+
+// ReadFile returns the named file content
+func (a *Archive) ReadFile(name string) ([]byte, error) {
+	file, err := a.GetFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't get %s file(%s): %w", a.kindName(), name, err)
+	}
+	return a.readFile(file)
+}
+
+func (a *Archive) readFile(file File) ([]byte, error) {
+	archiveReader, archiveCloser, err := a.openReader()
+	if err != nil {
+		return nil, err
+	}
+	defer archiveCloser.Close()
+
+	fb := make([]byte, file.Size)
+	_, err = io.ReadFull(openFileAt(archiveReader, file), fb)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %s file(%s): %w", a.kindName(), file.Name, err)
+	}
+	return fb, nil
+}
+
+func (a *Archive) openFile(file File) (io.ReadCloser, error) {
+	archiveReader, archiveCloser, err := a.openReader()
+	if err != nil {
+		return nil, err
+	}
+	return fileContent{Reader: openFileAt(archiveReader, file), Closer: archiveCloser}, nil
+}
+
+func openFileAt(archiveReader io.ReaderAt, file File) io.Reader {
+	return io.NewSectionReader(archiveReader, int64(file.Offset), int64(file.Size))
+}
+
+type fileContent struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,169 @@
+package rawarchive
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// FS returns a read-only file system with all archive files placed in its root directory.
+func (a *Archive) FS() fs.FS {
+	if a.filesIndexes == nil {
+		a.indexFiles()
+	}
+	return archiveFS{archive: a}
+}
+
+type archiveFS struct {
+	archive *Archive
+}
+
+func (afs archiveFS) Open(name string) (fs.File, error) {
+	if name == "." {
+		return &archiveDir{entries: afs.sortedDirEntries()}, nil
+	}
+	fileMeta, err := afs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := afs.archive.openFile(fileMeta)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &archiveFile{meta: fileMeta, content: content}, nil
+}
+
+func (afs archiveFS) Stat(name string) (fs.FileInfo, error) {
+	if name == "." {
+		return archiveDirInfo{}, nil
+	}
+	fileMeta, err := afs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return archiveFileInfo{meta: fileMeta}, nil
+}
+
+func (afs archiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		if _, err := afs.lookup("readdir", name); err != nil {
+			return nil, err
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return afs.sortedDirEntries(), nil
+}
+
+func (afs archiveFS) ReadFile(name string) ([]byte, error) {
+	if name == "." {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	fileMeta, err := afs.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := afs.archive.readFile(fileMeta)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return content, nil
+}
+
+func (afs archiveFS) lookup(op, name string) (File, error) {
+	if !fs.ValidPath(name) {
+		return File{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	fileMeta, err := afs.archive.GetFile(name)
+	if errors.Is(err, errNoSuchFile) {
+		return File{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		return File{}, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return fileMeta, nil
+}
+
+func (afs archiveFS) sortedDirEntries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(afs.archive.Files))
+	for _, file := range afs.archive.Files {
+		entries = append(entries, fs.FileInfoToDirEntry(archiveFileInfo{meta: file}))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+type archiveFile struct {
+	meta    File
+	content io.ReadCloser
+}
+
+func (f *archiveFile) Stat() (fs.FileInfo, error) {
+	return archiveFileInfo{meta: f.meta}, nil
+}
+
+func (f *archiveFile) Read(b []byte) (int, error) {
+	return f.content.Read(b)
+}
+
+func (f *archiveFile) Close() error {
+	return f.content.Close()
+}
+
+type archiveFileInfo struct {
+	meta File
+}
+
+func (fi archiveFileInfo) Name() string       { return fi.meta.Name }
+func (fi archiveFileInfo) Size() int64        { return int64(fi.meta.Size) }
+func (fi archiveFileInfo) Mode() fs.FileMode  { return 0444 }
+func (fi archiveFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi archiveFileInfo) IsDir() bool        { return false }
+func (fi archiveFileInfo) Sys() interface{}   { return fi.meta }
+
+type archiveDirInfo struct{}
+
+func (archiveDirInfo) Name() string       { return "." }
+func (archiveDirInfo) Size() int64        { return 0 }
+func (archiveDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (archiveDirInfo) ModTime() time.Time { return time.Time{} }
+func (archiveDirInfo) IsDir() bool        { return true }
+func (archiveDirInfo) Sys() interface{}   { return nil }
+
+type archiveDir struct {
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *archiveDir) Stat() (fs.FileInfo, error) {
+	return archiveDirInfo{}, nil
+}
+
+func (d *archiveDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+func (d *archiveDir) Close() error {
+	return nil
+}
+
+func (d *archiveDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
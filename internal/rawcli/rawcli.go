@@ -0,0 +1,169 @@
+// Package rawcli is the command line shared by sndutils and vidutils, their archives differ only in parsing
+package rawcli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/netscrn/homm3utils/internal/rawarchive"
+)
+
+// Tool describes a utility, like sndutils for .snd archives
+type Tool struct {
+	Name string
+	// Extension of archives shown in usage, like ".snd"
+	Extension string
+	Load      func(pathToArchive string) (*rawarchive.Archive, error)
+}
+
+type command func(args []string) error
+
+// Main runs a command given by os.Args and exits on its error
+func (t Tool) Main() {
+	commands := map[string]command{
+		"extract": t.runExtract,
+		"json":    t.runJson,
+		"list":    t.runList,
+	}
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s [command] <args>, commands: extract (default), json, list\n", t.Name)
+		os.Exit(2)
+	}
+
+	// without a known command the arguments are treated as extract arguments
+	name, cmd, args := "extract", commands["extract"], os.Args[1:]
+	if c, ok := commands[os.Args[1]]; ok {
+		name, cmd, args = os.Args[1], c, os.Args[2:]
+	}
+	exitOnError(name, cmd(args))
+}
+
+func exitOnError(name string, err error) {
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+func (t Tool) runList(args []string) error {
+	archive, err := t.loadArg("list", args)
+	if err != nil {
+		return err
+	}
+	for _, file := range archive.Files {
+		fmt.Printf("%-40s %10d %10d\n", file.Name, file.Offset, file.Size)
+	}
+	return nil
+}
+
+func (t Tool) runJson(args []string) error {
+	archive, err := t.loadArg("json", args)
+	if err != nil {
+		return err
+	}
+	archiveJSON, err := archive.ToJSON()
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(archiveJSON))
+	return nil
+}
+
+func (t Tool) loadArg(name string, args []string) (*rawarchive.Archive, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s <%s file>\n", t.Name, name, t.Extension)
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, errors.New("wrong arguments count")
+	}
+	return t.Load(fs.Arg(0))
+}
+
+func (t Tool) runExtract(args []string) error {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	concurrencyLevel := fs.Int("c", 0, "number of extracting goroutines, 0 means default")
+	continueOnError := fs.Bool("continue", false, "continue extracting after a file fails")
+	verbose := fs.Bool("v", false, "print each extracted file")
+	include := fs.String("include", "", "extract only files matching the glob pattern, case is ignored")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s extract [flags] <%s file> <out dir>\n", t.Name, t.Extension)
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("wrong arguments count")
+	}
+	pathToArchive, dstDir := fs.Arg(0), fs.Arg(1)
+	dstDirInfo, err := os.Stat(dstDir)
+	if err != nil {
+		return err
+	}
+	if !dstDirInfo.IsDir() {
+		return errors.New(dstDir + ": is not directory")
+	}
+
+	var filter func(rawarchive.File) bool
+	if *include != "" {
+		pattern := strings.ToLower(*include)
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern(%s): %w", *include, err)
+		}
+		filter = func(file rawarchive.File) bool {
+			matched, _ := filepath.Match(pattern, strings.ToLower(file.Name))
+			return matched
+		}
+	}
+
+	archive, err := t.Load(pathToArchive)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var last rawarchive.ExtractProgress
+	opts := rawarchive.ExtractOptions{
+		ConcurrencyLevel: *concurrencyLevel,
+		ContinueOnError:  *continueOnError,
+		Filter:           filter,
+		Progress: func(p rawarchive.ExtractProgress) {
+			last = p
+			if *verbose {
+				fmt.Printf("[%d/%d] %s\n", p.FilesDone, p.FilesTotal, p.File.Name)
+			}
+		},
+	}
+	err = rawarchive.Extract(ctx, archive, dstDir, opts)
+
+	var extractErrs rawarchive.ExtractErrors
+	if errors.As(err, &extractErrs) {
+		for _, e := range extractErrs {
+			fmt.Fprintln(os.Stderr, e.Error())
+		}
+	}
+	fmt.Printf("Extracted %d of %d files (%d bytes)\n", last.FilesDone, last.FilesTotal, last.BytesWritten)
+	if len(extractErrs) != 0 {
+		return fmt.Errorf("%d files failed", len(extractErrs))
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package lodconv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Codepage maps bytes 0x80-0xFF of a single byte windows codepage to runes, lower bytes are ASCII
+type Codepage struct {
+	Name  string
+	runes [128]rune
+}
+
+// Windows1251 is used by russian game versions
+var Windows1251 = newCodepage("windows-1251", [128]rune{
+	0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021, 0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+	0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, utf8.RuneError, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+	0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7, 0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+	0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7, 0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+}, func(b int) rune {
+	// 0xC0-0xFF are А-я in alphabetical order
+	return rune(0x0410 + b - 0xC0)
+})
+
+// Windows1252 is used by english and other western game versions
+var Windows1252 = newCodepage("windows-1252", [128]rune{
+	0x20AC, utf8.RuneError, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021, 0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, utf8.RuneError, 0x017D, utf8.RuneError,
+	utf8.RuneError, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, utf8.RuneError, 0x017E, 0x0178,
+}, func(b int) rune {
+	// 0xA0-0xFF match latin-1
+	return rune(b)
+})
+
+// newCodepage fills runes of bytes not listed in the table by rest
+func newCodepage(name string, table [128]rune, rest func(b int) rune) Codepage {
+	cp := Codepage{Name: name, runes: table}
+	for b := 0x80; b <= 0xFF; b++ {
+		if cp.runes[b-0x80] == 0 {
+			cp.runes[b-0x80] = rest(b)
+		}
+	}
+	return cp
+}
+
+// CodepageByName returns a codepage by its name or number, like windows-1251 or 1251
+func CodepageByName(name string) (Codepage, error) {
+	switch name {
+	case "1251", "cp1251", "windows-1251":
+		return Windows1251, nil
+	case "1252", "cp1252", "windows-1252":
+		return Windows1252, nil
+	}
+	return Codepage{}, fmt.Errorf("unknown codepage(%s)", name)
+}
+
+// Decode converts codepage encoded text into utf-8
+func (cp Codepage) Decode(text []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(text))
+	for _, b := range text {
+		sb.WriteRune(cp.rune(b))
+	}
+	return sb.String()
+}
+
+// DecodeTo converts codepage encoded text read from r into utf-8 written to w
+func (cp Codepage) DecodeTo(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		_, err = bw.WriteRune(cp.rune(b))
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (cp Codepage) rune(b byte) rune {
+	if b < 0x80 {
+		return rune(b)
+	}
+	return cp.runes[b-0x80]
+}
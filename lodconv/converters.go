@@ -0,0 +1,76 @@
+// Package lodconv holds converters of game formats into common ones, they are used to convert files on lod extraction
+package lodconv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/netscrn/homm3utils/defparse"
+	"github.com/netscrn/homm3utils/lodparse"
+	"github.com/netscrn/homm3utils/pcxparse"
+)
+
+// DefToPNG extracts def frames as png files into a directory named after the def file without extension
+func DefToPNG() lodparse.LodFileConverter {
+	return func(file lodparse.LodFileMeta, content io.Reader, dstDir string) error {
+		// def frames are read by offsets, so the whole def is kept in memory
+		defContent, err := io.ReadAll(content)
+		if err != nil {
+			return fmt.Errorf("can't read def(%s): %w", file.Name, err)
+		}
+		return defparse.ExtractDefReader(bytes.NewReader(defContent), trimExt(file.Name), dstDir)
+	}
+}
+
+// PcxToPNG writes a pcx image as <name without extension>.png
+func PcxToPNG() lodparse.LodFileConverter {
+	return func(file lodparse.LodFileMeta, content io.Reader, dstDir string) error {
+		img, err := pcxparse.DecodePcx(bufio.NewReader(content))
+		if err != nil {
+			return fmt.Errorf("can't decode pcx(%s): %w", file.Name, err)
+		}
+		return writeConverted(filepath.Join(dstDir, trimExt(file.Name)+".png"), func(w io.Writer) error {
+			return png.Encode(w, img)
+		})
+	}
+}
+
+// TxtToUTF8 decodes a text file from the codepage and writes it as <name without extension>.utf8.txt
+func TxtToUTF8(cp Codepage) lodparse.LodFileConverter {
+	return func(file lodparse.LodFileMeta, content io.Reader, dstDir string) error {
+		return writeConverted(filepath.Join(dstDir, trimExt(file.Name)+".utf8.txt"), func(w io.Writer) error {
+			return cp.DecodeTo(w, content)
+		})
+	}
+}
+
+// writeConverted removes the partially written file on error
+func writeConverted(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("can't create file(%s): %w", path, err)
+	}
+	bw := bufio.NewWriter(f)
+	err = write(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("can't write file(%s): %w", path, err)
+	}
+	return nil
+}
+
+func trimExt(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
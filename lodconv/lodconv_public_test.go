@@ -0,0 +1,137 @@
+package lodconv_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/netscrn/homm3utils/lodconv"
+	"github.com/netscrn/homm3utils/lodparse"
+)
+
+// files of the lodparse test archive are used, lodconv has no own test data
+var testFilesDir = filepath.Join("..", "lodparse", "testdata", "HotA_lng_files")
+
+// genrlTxtLine is the second line of GENRLTXT.TXT of the russian HotA
+const genrlTxtLine = "Добавление менеджера невозможно!"
+
+func convertTestFile(t *testing.T, convert lodparse.LodFileConverter, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(testFilesDir, name))
+	if err != nil {
+		t.Fatalf("Can't read test file: %v", err)
+	}
+	dstDir := t.TempDir()
+	file := lodparse.LodFileMeta{Name: name, OriginalSize: uint32(len(content))}
+	err = convert(file, bytes.NewReader(content), dstDir)
+	if err != nil {
+		t.Fatalf("Can't convert file(%s): %v", name, err)
+	}
+	return dstDir
+}
+
+func decodeTestPng(t *testing.T, path string) image.Image {
+	t.Helper()
+	pngFile, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Can't open converted png: %v", err)
+	}
+	defer pngFile.Close()
+	img, err := png.Decode(pngFile)
+	if err != nil {
+		t.Fatalf("Can't decode converted png(%s): %v", path, err)
+	}
+	return img
+}
+
+func TestCodepageDecode(t *testing.T) {
+	if decoded := lodconv.Windows1251.Decode([]byte{'A', 0xC0, 0xFF, 0xA8, 0xB8}); decoded != "AАяЁё" {
+		t.Errorf("Wrong windows-1251 decoding: %q", decoded)
+	}
+	if decoded := lodconv.Windows1252.Decode([]byte{'A', 0x80, 0xE9, 0xFF}); decoded != "A€éÿ" {
+		t.Errorf("Wrong windows-1252 decoding: %q", decoded)
+	}
+
+	for _, name := range []string{"1251", "cp1251", "windows-1251"} {
+		cp, err := lodconv.CodepageByName(name)
+		if err != nil || cp.Name != lodconv.Windows1251.Name {
+			t.Errorf("Codepage(%s) is %q: %v", name, cp.Name, err)
+		}
+	}
+	if _, err := lodconv.CodepageByName("koi8-r"); err == nil {
+		t.Error("Expected error getting unknown codepage")
+	}
+}
+
+func TestTxtToUTF8(t *testing.T) {
+	dstDir := convertTestFile(t, lodconv.TxtToUTF8(lodconv.Windows1251), "GENRLTXT.TXT")
+
+	converted, err := os.ReadFile(filepath.Join(dstDir, "GENRLTXT.utf8.txt"))
+	if err != nil {
+		t.Fatalf("Can't read converted text: %v", err)
+	}
+	if !utf8.Valid(converted) {
+		t.Error("Converted text isn't valid utf-8")
+	}
+	lines := strings.SplitN(string(converted), "\n", 3)
+	if len(lines) < 2 || strings.TrimRight(lines[1], "\t\r") != genrlTxtLine {
+		t.Errorf("Converted text doesn't have line %q: %q", genrlTxtLine, lines[:len(lines)-1])
+	}
+
+	original, err := os.ReadFile(filepath.Join(testFilesDir, "GENRLTXT.TXT"))
+	if err != nil {
+		t.Fatalf("Can't read original text: %v", err)
+	}
+	if string(converted) != lodconv.Windows1251.Decode(original) {
+		t.Error("Converted text differs from decoded original")
+	}
+}
+
+func TestPcxToPNG(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		x, y          int
+		expected      color.RGBA
+	}{
+		{name: "AdvOpts.pcx", width: 289, height: 387, x: 144, y: 193, expected: color.RGBA{R: 66, G: 41, B: 24, A: 255}},
+		{name: "GamSelBk.pcx", width: 800, height: 600, x: 400, y: 300, expected: color.RGBA{R: 92, G: 97, B: 85, A: 255}},
+	}
+	for _, test := range tests {
+		dstDir := convertTestFile(t, lodconv.PcxToPNG(), test.name)
+		img := decodeTestPng(t, filepath.Join(dstDir, strings.TrimSuffix(test.name, ".pcx")+".png"))
+		if img.Bounds() != image.Rect(0, 0, test.width, test.height) {
+			t.Errorf("Png of pcx(%s) is %v, expected %dx%d", test.name, img.Bounds(), test.width, test.height)
+		}
+		if c := color.RGBAModel.Convert(img.At(test.x, test.y)); c != test.expected {
+			t.Errorf("Pixel(%d, %d) of png of pcx(%s) is %v, expected %v", test.x, test.y, test.name, c, test.expected)
+		}
+	}
+
+	dstDir := t.TempDir()
+	err := lodconv.PcxToPNG()(lodparse.LodFileMeta{Name: "broken.pcx"}, bytes.NewReader([]byte{1, 2, 3}), dstDir)
+	if err == nil {
+		t.Error("Expected error converting broken pcx")
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "broken.png")); !os.IsNotExist(err) {
+		t.Error("Png of broken pcx is written")
+	}
+}
+
+func TestDefToPNG(t *testing.T) {
+	dstDir := convertTestFile(t, lodconv.DefToPNG(), "AVArnd1.def")
+
+	img := decodeTestPng(t, filepath.Join(dstDir, "AVArnd1", "0", "r_art1.png"))
+	if img.Bounds() != image.Rect(0, 0, 64, 32) {
+		t.Errorf("Frame png of def is %v, expected 64x32", img.Bounds())
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "AVArnd1", "meta.json")); err != nil {
+		t.Errorf("Def meta isn't written: %v", err)
+	}
+}
@@ -1,10 +1,14 @@
 package lodparse
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func LoadLodArchiveMetaFromJson(pathFoJson string) (*LodArchiveMeta, error) {
@@ -27,22 +31,83 @@ func LoadLodArchiveMetaFromLodFile(pathToLod string) (*LodArchiveMeta, error) {
 	return parseLodFile(pathToLod)
 }
 
+var errNoSuchFile = errors.New("no such file in archive")
+
 type lodArchiveFileIndex map[string]int
 type LodArchiveMeta struct {
 	ArchiveFilePath string         `json:"file_path"`
 	LodType         LodArchiveType `json:"lod_type"`
 	NumberOfFiles   uint32         `json:"number_of_files"`
 	Files           []LodFileMeta  `json:"files"`
-	filesIndexes    lodArchiveFileIndex
+	// Reserved holds the header bytes following the number of files, their meaning is unknown
+	Reserved HexBytes `json:"reserved,omitempty"`
+	// TableSize is the number of files table slots when more slots are reserved than NumberOfFiles
+	TableSize uint32 `json:"table_size,omitempty"`
+	// UnusedSlots holds raw bytes of reserved table slots after the last file, it's kept only when they aren't zeros
+	UnusedSlots HexBytes `json:"unused_slots,omitempty"`
+
+	filesIndexes  lodArchiveFileIndex
+	foldedIndexes lodArchiveFileIndex
+	reader        io.ReaderAt
+	size          int64
+	// pending holds sources of added and replaced files until the archive is saved
+	pending map[string]LodFileSource
+	changed bool
+	// hashesOnly is set for metas of manifests, they have no content to read, so stored hashes are trusted
+	hashesOnly bool
+}
+
+// openReader returns the reader the meta was parsed from or opens the archive by its path
+func (lam *LodArchiveMeta) openReader() (io.ReaderAt, io.Closer, error) {
+	if lam.reader != nil {
+		return lam.reader, nopCloser{}, nil
+	}
+	lodFile, err := os.Open(lam.ArchiveFilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't open lod archive(%s): %w", lam.ArchiveFilePath, err)
+	}
+	return lodFile, lodFile, nil
 }
 
+// archiveSize returns the size of the archive read by lodReader returned from openReader
+func (lam *LodArchiveMeta) archiveSize(lodReader io.ReaderAt) (int64, error) {
+	if lam.reader != nil {
+		return lam.size, nil
+	}
+	statReader, ok := lodReader.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return 0, fmt.Errorf("can't get size of lod archive(%s), its reader has no Stat", lam.ArchiveFilePath)
+	}
+	lodFileInfo, err := statReader.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("can't stat lod archive(%s): %w", lam.ArchiveFilePath, err)
+	}
+	return lodFileInfo.Size(), nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
 func (lam *LodArchiveMeta) indexFiles() {
 	lam.filesIndexes = make(lodArchiveFileIndex, len(lam.Files))
+	lam.foldedIndexes = make(lodArchiveFileIndex, len(lam.Files))
 	for i, file := range lam.Files {
 		lam.filesIndexes[file.Name] = i
+		// the first file wins when names collide in different case
+		folded := foldName(file.Name)
+		if _, ok := lam.foldedIndexes[folded]; !ok {
+			lam.foldedIndexes[folded] = i
+		}
 	}
 }
 
+func foldName(name string) string {
+	return strings.ToLower(name)
+}
+
+// GetFile looks a file up by its name ignoring case, the same way the game resolves resources.
+// A file with exactly matching name is preferred over files colliding in different case.
 func (lam *LodArchiveMeta) GetFile(name string) (LodFileMeta, error) {
 	if lam.filesIndexes == nil {
 		lam.indexFiles()
@@ -54,7 +119,10 @@ func (lam *LodArchiveMeta) GetFile(name string) (LodFileMeta, error) {
 
 	fi, ok := lam.filesIndexes[name]
 	if !ok {
-		return LodFileMeta{}, errors.New("no such file in archive")
+		fi, ok = lam.foldedIndexes[foldName(name)]
+	}
+	if !ok {
+		return LodFileMeta{}, errNoSuchFile
 	}
 
 	if fi >= len(lam.Files) {
@@ -89,9 +157,40 @@ type LodFileMeta struct {
 	Offset         uint32 `json:"offset"`
 	OriginalSize   uint32 `json:"original_size"`
 	CompressedSize uint32 `json:"compressed_size"`
+	// Unknown is the table entry field between OriginalSize and CompressedSize, its meaning is unknown
+	Unknown uint32 `json:"unknown,omitempty"`
+	// NameField is the raw name field of the table entry, it's kept only when bytes after the name terminator
+	// aren't zeros, original packers left garbage there
+	NameField HexBytes `json:"name_field,omitempty"`
+	// SHA256 and CRC32 of the original content are hex encoded, they are set only by ComputeHashes
+	SHA256 string `json:"sha256,omitempty"`
+	CRC32  string `json:"crc32,omitempty"`
+}
+
+// HexBytes is a byte slice encoded as a hex string in JSON
+type HexBytes []byte
+
+func (hb HexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(hb)), nil
+}
+
+func (hb *HexBytes) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	*hb = b
+	return nil
 }
 
 func (lf LodFileMeta) IsCompressed() bool {
 	return lf.CompressedSize != 0
 }
 
+func (lf LodFileMeta) storedSize() uint32 {
+	if lf.IsCompressed() {
+		return lf.CompressedSize
+	}
+	return lf.OriginalSize
+}
+
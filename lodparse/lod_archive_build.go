@@ -0,0 +1,44 @@
+package lodparse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BuildLodArchive writes a new lod archive at pathToLod as described by recipe, which is usually
+// loaded by LoadLodArchiveMetaFromJson. Files are read from srcDir by their names and written in the recipe order,
+// a file is compressed when its recipe CompressedSize is not zero. Offsets and sizes of the recipe are ignored,
+// except that the number of reserved table slots is derived from offsets when the recipe has no TableSize.
+func BuildLodArchive(recipe *LodArchiveMeta, srcDir, pathToLod string) (*LodArchiveMeta, error) {
+	if recipe.NumberOfFiles != 0 && int(recipe.NumberOfFiles) != len(recipe.Files) {
+		return nil, fmt.Errorf("lod recipe tells %d files, but has %d", recipe.NumberOfFiles, len(recipe.Files))
+	}
+
+	sources := make([]LodFileSource, 0, len(recipe.Files))
+	for _, file := range recipe.Files {
+		path := filepath.Join(srcDir, file.Name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("can't find source of lod file(%s): %w", file.Name, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("source of lod file(%s) is not a regular file", file.Name)
+		}
+
+		compression := CompressNever
+		if file.IsCompressed() {
+			compression = CompressAlways
+		}
+		source := fileSource(file.Name, path, compression)
+		source.Unknown = file.Unknown
+		source.NameField = file.NameField
+		sources = append(sources, source)
+	}
+
+	layout := headerLayoutOf(recipe)
+	if layout.tableSize == 0 {
+		layout.tableSize = tableSizeBeforeContent(recipe.Files)
+	}
+	return createLodArchive(pathToLod, layout, sources)
+}
@@ -0,0 +1,220 @@
+package lodparse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrUnsavedChanges is returned by functions checking the archive file itself while the meta has unsaved changes
+var ErrUnsavedChanges = errors.New("lod archive has unsaved changes")
+
+// Add adds a new file to the archive, the content is read from the source on Save.
+// The source is read once to know the file size, so the file can be read and extracted before Save.
+// When archive files are ordered case-insensitively by name the order is kept, otherwise the file is appended.
+func (lam *LodArchiveMeta) Add(source LodFileSource) error {
+	if _, err := lam.GetFile(source.Name); err == nil {
+		return fmt.Errorf("lod file(%s) already exists", source.Name)
+	}
+	err := validateLodFileSources([]LodFileSource{source})
+	if err != nil {
+		return err
+	}
+	size, err := sourceSize(source)
+	if err != nil {
+		return err
+	}
+
+	pos := len(lam.Files)
+	if lam.isOrderedByName() {
+		pos = sort.Search(len(lam.Files), func(i int) bool {
+			return foldName(lam.Files[i].Name) > foldName(source.Name)
+		})
+	}
+	lam.Files = append(lam.Files, LodFileMeta{})
+	copy(lam.Files[pos+1:], lam.Files[pos:])
+	lam.Files[pos] = LodFileMeta{Name: source.Name, OriginalSize: size, Unknown: source.Unknown}
+
+	lam.setPending(source.Name, source)
+	lam.filesChanged()
+	return nil
+}
+
+// Replace replaces content of an existing file keeping its name and position in the archive
+func (lam *LodArchiveMeta) Replace(source LodFileSource) error {
+	file, err := lam.GetFile(source.Name)
+	if err != nil {
+		return fmt.Errorf("can't replace lod file(%s): %w", source.Name, err)
+	}
+	if source.Open == nil {
+		return fmt.Errorf("lod file(%s) has no content source", source.Name)
+	}
+	size, err := sourceSize(source)
+	if err != nil {
+		return err
+	}
+
+	source.Name = file.Name
+	source.Unknown = file.Unknown
+	source.NameField = file.NameField
+	// the file isn't stored in the archive until Save, so it has no offset and compressed size
+	lam.Files[lam.filesIndexes[file.Name]] = LodFileMeta{Name: file.Name, OriginalSize: size, Unknown: file.Unknown, NameField: file.NameField}
+	lam.setPending(file.Name, source)
+	return nil
+}
+
+// Remove removes the named file from the archive
+func (lam *LodArchiveMeta) Remove(name string) error {
+	file, err := lam.GetFile(name)
+	if err != nil {
+		return fmt.Errorf("can't remove lod file(%s): %w", name, err)
+	}
+
+	fi := lam.filesIndexes[file.Name]
+	lam.Files = append(lam.Files[:fi], lam.Files[fi+1:]...)
+	delete(lam.pending, file.Name)
+	lam.filesChanged()
+	return nil
+}
+
+// Rename renames the file keeping its content and position in the archive
+func (lam *LodArchiveMeta) Rename(oldName, newName string) error {
+	file, err := lam.GetFile(oldName)
+	if err != nil {
+		return fmt.Errorf("can't rename lod file(%s): %w", oldName, err)
+	}
+	if existing, err := lam.GetFile(newName); err == nil && existing.Name != file.Name {
+		return fmt.Errorf("lod file(%s) already exists", newName)
+	}
+	err = validateLodFileName(newName)
+	if err != nil {
+		return err
+	}
+
+	// the raw name field holds the old name, so it's dropped
+	lam.Files[lam.filesIndexes[file.Name]].Name = newName
+	lam.Files[lam.filesIndexes[file.Name]].NameField = nil
+	if source, ok := lam.pending[file.Name]; ok {
+		delete(lam.pending, file.Name)
+		source.Name = newName
+		source.NameField = nil
+		lam.setPending(newName, source)
+	}
+	lam.filesChanged()
+	return nil
+}
+
+// HasChanges tells whether files were added, replaced, removed or renamed since the archive was loaded or saved
+func (lam *LodArchiveMeta) HasChanges() bool {
+	return lam.changed
+}
+
+// Save writes the archive with all changes applied to pathToLod, which may be the path of the archive itself.
+// Files without changes are copied as stored in the archive, gaps between files are dropped.
+// Reserved header bytes, unknown entry fields and reserved table slots are kept,
+// so an archive saved without changes is reproduced byte-for-byte when it had no gaps.
+func (lam *LodArchiveMeta) Save(pathToLod string) error {
+	sources := make([]LodFileSource, 0, len(lam.Files))
+	needsReader := false
+	for _, file := range lam.Files {
+		if source, ok := lam.pending[file.Name]; ok {
+			sources = append(sources, source)
+			continue
+		}
+		sources = append(sources, LodFileSource{Name: file.Name, Unknown: file.Unknown, NameField: file.NameField, stored: &storedLodFile{meta: file}})
+		needsReader = true
+	}
+
+	var lodCloser io.Closer = nopCloser{}
+	if needsReader {
+		lodReader, closer, err := lam.openReader()
+		if err != nil {
+			return err
+		}
+		lodCloser = closer
+		defer lodCloser.Close()
+		for i := range sources {
+			if sources[i].stored != nil {
+				sources[i].stored.lodReader = lodReader
+			}
+		}
+	}
+
+	// the archive is written into a temporary file first, because it may be read during writing
+	tmpFile, err := os.CreateTemp(filepath.Dir(pathToLod), filepath.Base(pathToLod)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("can't create temporary lod archive: %w", err)
+	}
+	saved, err := writeLodArchive(tmpFile, headerLayoutOf(lam), sources)
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("can't save lod archive(%s): %w", pathToLod, err)
+	}
+	err = tmpFile.Chmod(0644)
+	if err == nil {
+		err = tmpFile.Close()
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("can't save lod archive(%s): %w", pathToLod, err)
+	}
+	// the source archive is closed before it may be replaced by the saved one
+	lodCloser.Close()
+	err = os.Rename(tmpFile.Name(), pathToLod)
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("can't save lod archive(%s): %w", pathToLod, err)
+	}
+
+	lam.ArchiveFilePath = pathToLod
+	lam.reader = nil
+	lam.Files = saved.Files
+	lam.NumberOfFiles = saved.NumberOfFiles
+	lam.TableSize = saved.TableSize
+	lam.pending = nil
+	lam.changed = false
+	lam.indexFiles()
+	return nil
+}
+
+// sourceSize reads the whole source content to know its size
+func sourceSize(source LodFileSource) (uint32, error) {
+	content, err := source.Open()
+	if err != nil {
+		return 0, fmt.Errorf("can't open source of lod file(%s): %w", source.Name, err)
+	}
+	defer content.Close()
+	size, err := io.Copy(io.Discard, content)
+	if err != nil {
+		return 0, fmt.Errorf("can't read source of lod file(%s): %w", source.Name, err)
+	}
+	if size > math.MaxUint32 {
+		return 0, fmt.Errorf("lod file(%s) content exceeds 4GB", source.Name)
+	}
+	return uint32(size), nil
+}
+
+func (lam *LodArchiveMeta) setPending(name string, source LodFileSource) {
+	if lam.pending == nil {
+		lam.pending = make(map[string]LodFileSource)
+	}
+	lam.pending[name] = source
+	lam.changed = true
+}
+
+func (lam *LodArchiveMeta) filesChanged() {
+	lam.NumberOfFiles = uint32(len(lam.Files))
+	lam.changed = true
+	lam.indexFiles()
+}
+
+func (lam *LodArchiveMeta) isOrderedByName() bool {
+	return sort.SliceIsSorted(lam.Files, func(i, j int) bool {
+		return foldName(lam.Files[i].Name) < foldName(lam.Files[j].Name)
+	})
+}
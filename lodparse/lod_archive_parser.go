@@ -1,6 +1,7 @@
 package lodparse
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -12,19 +13,41 @@ import (
 // 0x00444f4c is the special value that each lod archive starts with
 const lodArchiveHeader uint32 = 0x00444f4c
 
-func parseLodFile(pathToLod string) (*LodArchiveMeta, error) {
-	lodArchiveMeta := LodArchiveMeta{
-		ArchiveFilePath: pathToLod,
+// ParseLod reads lod archive meta from r, size is the size of the whole archive.
+// The returned meta keeps r to read archive files, so r should stay readable while the meta is in use.
+func ParseLod(r io.ReaderAt, size int64) (*LodArchiveMeta, error) {
+	lodArchiveMeta, err := parseLod(r, size, "")
+	if err != nil {
+		return nil, err
 	}
+	lodArchiveMeta.reader = r
+	lodArchiveMeta.size = size
+	return lodArchiveMeta, nil
+}
 
-	lodFileReader, err := os.Open(pathToLod)
+func parseLodFile(pathToLod string) (*LodArchiveMeta, error) {
+	lodFile, err := os.Open(pathToLod)
 	if err != nil {
 		return nil, fmt.Errorf("can't open lod archive(%s): %w", pathToLod, err)
 	}
-	defer lodFileReader.Close()
+	defer lodFile.Close()
+
+	lodFileInfo, err := lodFile.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("can't stat lod archive(%s): %w", pathToLod, err)
+	}
+
+	return parseLod(lodFile, lodFileInfo.Size(), pathToLod)
+}
+
+func parseLod(r io.ReaderAt, size int64, pathToLod string) (*LodArchiveMeta, error) {
+	lodArchiveMeta := LodArchiveMeta{
+		ArchiveFilePath: pathToLod,
+	}
+	lodFileReader := io.NewSectionReader(r, 0, size)
 
 	var lodHeader uint32
-	err = binread.ReadUint32(lodFileReader, &lodHeader)
+	err := binread.ReadUint32(lodFileReader, &lodHeader)
 	if err != nil {
 		return nil, fmt.Errorf("can't read header of lod archive (%s): %w", pathToLod, err)
 	}
@@ -47,33 +70,53 @@ func parseLodFile(pathToLod string) (*LodArchiveMeta, error) {
 	if lodArchiveMeta.NumberOfFiles == 0 {
 		return nil, errors.New("lod archive is empty")
 	}
+	if int64(lodHeaderSize)+int64(lodFileEntrySize)*int64(lodArchiveMeta.NumberOfFiles) > size {
+		return nil, fmt.Errorf("lod archive(%s) is too small for %d files", pathToLod, lodArchiveMeta.NumberOfFiles)
+	}
 
-	_, err = lodFileReader.Seek(80, io.SeekCurrent)
+	lodArchiveMeta.Reserved = make(HexBytes, lodReservedHeaderSize)
+	_, err = io.ReadFull(lodFileReader, lodArchiveMeta.Reserved)
 	if err != nil {
-		return nil, fmt.Errorf("can't seek on lod archive(%s): %w", pathToLod, err)
+		return nil, fmt.Errorf("can't read reserved header of lod archive(%s): %w", pathToLod, err)
 	}
 
 	lodArchiveMeta.Files, err = readLodFiles(lodFileReader, lodArchiveMeta.NumberOfFiles)
 	if err != nil {
 		return nil, fmt.Errorf("can't read files of lod archive(%s): %w", pathToLod, err)
 	}
+	lodArchiveMeta.TableSize = tableSizeBeforeContent(lodArchiveMeta.Files)
+	if lodArchiveMeta.TableSize != 0 {
+		unusedSlots := make(HexBytes, lodFileEntrySize*(lodArchiveMeta.TableSize-lodArchiveMeta.NumberOfFiles))
+		_, err = io.ReadFull(lodFileReader, unusedSlots)
+		if err != nil {
+			return nil, fmt.Errorf("can't read unused table slots of lod archive(%s): %w", pathToLod, err)
+		}
+		if !isZeroed(unusedSlots) {
+			lodArchiveMeta.UnusedSlots = unusedSlots
+		}
+	}
 	lodArchiveMeta.indexFiles()
 
 	return &lodArchiveMeta, nil
 }
 
-func readLodFiles(laf *os.File, numberOfFiles uint32) ([]LodFileMeta, error) {
+func readLodFiles(laf io.Reader, numberOfFiles uint32) ([]LodFileMeta, error) {
 	lodFiles := make([]LodFileMeta, 0, numberOfFiles)
 
 	var fi uint32
 	for fi = 0; fi < numberOfFiles; fi++ {
 		lodFile := LodFileMeta{}
 
-		name, err := binread.ReadAvailableChars(laf, 16)
+		nameField := make(HexBytes, lodFileNameSize)
+		_, err := io.ReadFull(laf, nameField)
 		if err != nil {
-			return nil, fmt.Errorf("error reading offset of [%d]: %w", fi, err)
+			return nil, fmt.Errorf("error reading name of [%d]: %w", fi, err)
 		}
+		name := nameFromField(nameField)
 		lodFile.Name = name
+		if !isZeroed(nameField[len(name):]) {
+			lodFile.NameField = nameField
+		}
 
 		err = binread.ReadUint32(laf, &lodFile.Offset)
 		if err != nil {
@@ -83,9 +126,9 @@ func readLodFiles(laf *os.File, numberOfFiles uint32) ([]LodFileMeta, error) {
 		if err != nil {
 			return nil, fmt.Errorf("error reading originalSize of [%d, %s]: %w", fi, name, err)
 		}
-		_, err = laf.Seek(4, io.SeekCurrent)
+		err = binread.ReadUint32(laf, &lodFile.Unknown)
 		if err != nil {
-			return nil, fmt.Errorf("can't seek unknown lod data part of [%d, %s]: %w", fi, name, err)
+			return nil, fmt.Errorf("error reading unknown lod data part of [%d, %s]: %w", fi, name, err)
 		}
 		err = binread.ReadUint32(laf, &lodFile.CompressedSize)
 		if err != nil {
@@ -97,3 +140,44 @@ func readLodFiles(laf *os.File, numberOfFiles uint32) ([]LodFileMeta, error) {
 
 	return lodFiles, nil
 }
+
+// nameFromField returns the name up to the terminating zero, a name taking the whole field has no terminator
+func nameFromField(nameField []byte) string {
+	nameLen := bytes.IndexByte(nameField, 0)
+	if nameLen == -1 {
+		nameLen = len(nameField)
+	}
+	return string(nameField[:nameLen])
+}
+
+func isZeroed(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// tableSizeBeforeContent returns the number of table slots that fit before the first file content,
+// game archives reserve more slots than they use. Zero is returned when no extra slots are reserved.
+func tableSizeBeforeContent(files []LodFileMeta) uint32 {
+	if len(files) == 0 {
+		return 0
+	}
+	firstOffset := files[0].Offset
+	for _, file := range files {
+		if file.Offset < firstOffset {
+			firstOffset = file.Offset
+		}
+	}
+
+	if firstOffset < lodHeaderSize || (firstOffset-lodHeaderSize)%lodFileEntrySize != 0 {
+		return 0
+	}
+	slots := (firstOffset - lodHeaderSize) / lodFileEntrySize
+	if slots <= uint32(len(files)) {
+		return 0
+	}
+	return slots
+}
@@ -1,5 +1,12 @@
 package lodparse
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type LodArchiveType uint32
 const (
 	Base      LodArchiveType = 0x01
@@ -7,6 +14,12 @@ const (
 )
 var unknownTypes = [2]LodArchiveType{0xff, 0x1F4}
 
+// lodArchiveTypeNames has only types of a known meaning, unknown types are kept as numbers
+var lodArchiveTypeNames = map[LodArchiveType]string{
+	Base:      "base",
+	Expansion: "expansion",
+}
+
 func (lat LodArchiveType) IsBaseType() bool {
 	return lat == Base
 }
@@ -22,4 +35,53 @@ func (lat LodArchiveType) IsUnknownType() bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// String returns the type name, types without a name are returned as numbers
+func (lat LodArchiveType) String() string {
+	if name, ok := lodArchiveTypeNames[lat]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(lat), 10)
+}
+
+// MarshalJSON encodes named types as strings and other types as numbers
+func (lat LodArchiveType) MarshalJSON() ([]byte, error) {
+	if name, ok := lodArchiveTypeNames[lat]; ok {
+		return json.Marshal(name)
+	}
+	return json.Marshal(uint32(lat))
+}
+
+// UnmarshalJSON accepts both type names and numbers, meta written before types got names has numbers
+func (lat *LodArchiveType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		parsed, err := ParseLodArchiveType(name)
+		if err != nil {
+			return err
+		}
+		*lat = parsed
+		return nil
+	}
+	var number uint32
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("lod type should be a name or a number: %w", err)
+	}
+	*lat = LodArchiveType(number)
+	return nil
+}
+
+// ParseLodArchiveType parses a type name, like base, or a number in any base Go accepts, like 0x1F4
+func ParseLodArchiveType(s string) (LodArchiveType, error) {
+	for lat, name := range lodArchiveTypeNames {
+		if strings.EqualFold(s, name) {
+			return lat, nil
+		}
+	}
+	number, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lod type(%s)", s)
+	}
+	return LodArchiveType(number), nil
+}
@@ -0,0 +1,355 @@
+package lodparse
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	lodReservedHeaderSize = 80
+	lodHeaderSize         = 12 + lodReservedHeaderSize
+	lodFileNameSize       = 16
+	lodFileEntrySize      = lodFileNameSize + 16
+)
+
+// LodCompression tells the writer whether an entry should be stored zlib compressed
+type LodCompression uint8
+
+const (
+	// CompressAuto compresses an entry only when it makes the entry smaller
+	CompressAuto LodCompression = iota
+	CompressAlways
+	CompressNever
+)
+
+// LodFileSource describes a single entry that should be written into a lod archive
+type LodFileSource struct {
+	Name        string
+	Compression LodCompression
+	Open        func() (io.ReadCloser, error)
+	// Unknown is written into the table entry field with unknown meaning
+	Unknown uint32
+	// NameField is written instead of the zero padded name when it holds the same name
+	NameField HexBytes
+	// stored is set for files copied as is from another lod archive
+	stored *storedLodFile
+}
+
+type storedLodFile struct {
+	meta      LodFileMeta
+	lodReader io.ReaderAt
+}
+
+// PackLodDir writes all regular files of srcDir into a new lod archive at pathToLod.
+// Entries are ordered case-insensitively by name, the same way original game archives are.
+func PackLodDir(srcDir, pathToLod string, lodType LodArchiveType, compression LodCompression) (*LodArchiveMeta, error) {
+	sources, err := dirFileSources(srcDir, compression)
+	if err != nil {
+		return nil, err
+	}
+	return CreateLodArchive(pathToLod, lodType, sources)
+}
+
+func dirFileSources(srcDir string, compression LodCompression) ([]LodFileSource, error) {
+	dirContent, err := os.ReadDir(srcDir)
+	if err != nil {
+		return nil, fmt.Errorf("can't read source dir(%s): %w", srcDir, err)
+	}
+
+	sources := make([]LodFileSource, 0, len(dirContent))
+	for _, entry := range dirContent {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		sources = append(sources, fileSource(entry.Name(), filepath.Join(srcDir, entry.Name()), compression))
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return strings.ToLower(sources[i].Name) < strings.ToLower(sources[j].Name)
+	})
+
+	return sources, nil
+}
+
+func fileSource(name, path string, compression LodCompression) LodFileSource {
+	return LodFileSource{
+		Name:        name,
+		Compression: compression,
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}
+}
+
+// CreateLodArchive writes sources into a new lod archive at pathToLod
+func CreateLodArchive(pathToLod string, lodType LodArchiveType, sources []LodFileSource) (*LodArchiveMeta, error) {
+	return createLodArchive(pathToLod, lodArchiveHeaderLayout{lodType: lodType}, sources)
+}
+
+func createLodArchive(pathToLod string, layout lodArchiveHeaderLayout, sources []LodFileSource) (*LodArchiveMeta, error) {
+	lodFile, err := os.Create(pathToLod)
+	if err != nil {
+		return nil, fmt.Errorf("can't create lod archive(%s): %w", pathToLod, err)
+	}
+
+	lam, err := writeLodArchive(lodFile, layout, sources)
+	if err != nil {
+		lodFile.Close()
+		os.Remove(pathToLod)
+		return nil, fmt.Errorf("can't write lod archive(%s): %w", pathToLod, err)
+	}
+	err = lodFile.Close()
+	if err != nil {
+		return nil, fmt.Errorf("can't close lod archive(%s): %w", pathToLod, err)
+	}
+
+	lam.ArchiveFilePath = pathToLod
+	return lam, nil
+}
+
+// WriteLodArchive writes the header, the files table and the content of sources in given order.
+// Entries are written one by one, so only a single entry is held in memory at a time.
+func WriteLodArchive(w io.WriteSeeker, lodType LodArchiveType, sources []LodFileSource) (*LodArchiveMeta, error) {
+	return writeLodArchive(w, lodArchiveHeaderLayout{lodType: lodType}, sources)
+}
+
+// lodArchiveHeaderLayout holds header values kept by the writer to reproduce parsed archives byte-for-byte
+type lodArchiveHeaderLayout struct {
+	lodType     LodArchiveType
+	reserved    []byte
+	tableSize   uint32
+	unusedSlots []byte
+}
+
+func headerLayoutOf(lam *LodArchiveMeta) lodArchiveHeaderLayout {
+	return lodArchiveHeaderLayout{
+		lodType:     lam.LodType,
+		reserved:    lam.Reserved,
+		tableSize:   lam.TableSize,
+		unusedSlots: lam.UnusedSlots,
+	}
+}
+
+func writeLodArchive(w io.WriteSeeker, layout lodArchiveHeaderLayout, sources []LodFileSource) (*LodArchiveMeta, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("lod archive is empty")
+	}
+	err := validateLodFileSources(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("can't seek on lod archive: %w", err)
+	}
+
+	if len(layout.reserved) > lodReservedHeaderSize {
+		return nil, fmt.Errorf("reserved header is longer than %d bytes", lodReservedHeaderSize)
+	}
+
+	lam := LodArchiveMeta{
+		LodType:       layout.lodType,
+		NumberOfFiles: uint32(len(sources)),
+		Files:         make([]LodFileMeta, 0, len(sources)),
+		Reserved:      layout.reserved,
+	}
+	tableSlots := len(sources)
+	if int(layout.tableSize) > tableSlots {
+		tableSlots = int(layout.tableSize)
+		lam.TableSize = layout.tableSize
+		lam.UnusedSlots = layout.unusedSlots
+	}
+
+	// the table is written twice: zeroed as a placeholder first and with real offsets and sizes at the end
+	tableSize := int64(lodHeaderSize + lodFileEntrySize*tableSlots)
+	_, err = w.Write(make([]byte, tableSize))
+	if err != nil {
+		return nil, fmt.Errorf("can't write lod archive files table: %w", err)
+	}
+
+	offset := tableSize
+	for _, source := range sources {
+		fileMeta, err := writeLodFile(w, source, offset)
+		if err != nil {
+			return nil, fmt.Errorf("can't write lod file(%s): %w", source.Name, err)
+		}
+		fileMeta.Unknown = source.Unknown
+		if nameFromField(source.NameField) == source.Name && len(source.NameField) == lodFileNameSize {
+			fileMeta.NameField = source.NameField
+		}
+		lam.Files = append(lam.Files, fileMeta)
+		offset += int64(fileMeta.storedSize())
+		if offset > 0xffffffff {
+			return nil, errors.New("lod archive exceeds 4GB")
+		}
+	}
+
+	_, err = w.Seek(start, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("can't seek on lod archive: %w", err)
+	}
+	err = writeLodHeader(w, &lam)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Seek(start+offset, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("can't seek on lod archive: %w", err)
+	}
+
+	lam.indexFiles()
+	return &lam, nil
+}
+
+func validateLodFileSources(sources []LodFileSource) error {
+	names := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		err := validateLodFileName(source.Name)
+		if err != nil {
+			return err
+		}
+		if names[source.Name] {
+			return fmt.Errorf("duplicated lod file name(%s)", source.Name)
+		}
+		names[source.Name] = true
+		if source.Open == nil && source.stored == nil {
+			return fmt.Errorf("lod file(%s) has no content source", source.Name)
+		}
+	}
+	return nil
+}
+
+func validateLodFileName(name string) error {
+	if len(name) == 0 {
+		return errors.New("lod file name is empty")
+	}
+	if len(name) >= lodFileNameSize {
+		return fmt.Errorf("lod file name(%s) is longer than %d characters", name, lodFileNameSize-1)
+	}
+	if strings.IndexByte(name, 0) != -1 {
+		return fmt.Errorf("lod file name(%q) contains null character", name)
+	}
+	return nil
+}
+
+func writeLodFile(w io.Writer, source LodFileSource, offset int64) (LodFileMeta, error) {
+	fileMeta := LodFileMeta{
+		Name:   source.Name,
+		Offset: uint32(offset),
+	}
+	if source.stored != nil {
+		return writeStoredLodFile(w, fileMeta, source.stored)
+	}
+
+	src, err := source.Open()
+	if err != nil {
+		return LodFileMeta{}, fmt.Errorf("can't open content: %w", err)
+	}
+	defer src.Close()
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return LodFileMeta{}, fmt.Errorf("can't read content: %w", err)
+	}
+	if uint64(len(content)) > 0xffffffff {
+		return LodFileMeta{}, errors.New("content exceeds 4GB")
+	}
+	fileMeta.OriginalSize = uint32(len(content))
+
+	if source.Compression != CompressNever {
+		compressed, err := compressLodFile(content)
+		if err != nil {
+			return LodFileMeta{}, err
+		}
+		if source.Compression == CompressAlways || len(compressed) < len(content) {
+			content = compressed
+			fileMeta.CompressedSize = uint32(len(compressed))
+		}
+	}
+
+	_, err = w.Write(content)
+	if err != nil {
+		return LodFileMeta{}, fmt.Errorf("can't write content: %w", err)
+	}
+	return fileMeta, nil
+}
+
+// writeStoredLodFile copies stored (possibly compressed) bytes without recompressing them
+func writeStoredLodFile(w io.Writer, fileMeta LodFileMeta, stored *storedLodFile) (LodFileMeta, error) {
+	fileMeta.OriginalSize = stored.meta.OriginalSize
+	fileMeta.CompressedSize = stored.meta.CompressedSize
+	fileMeta.SHA256 = stored.meta.SHA256
+	fileMeta.CRC32 = stored.meta.CRC32
+
+	storedSize := int64(stored.meta.storedSize())
+	written, err := io.Copy(w, io.NewSectionReader(stored.lodReader, int64(stored.meta.Offset), storedSize))
+	if err != nil {
+		return LodFileMeta{}, fmt.Errorf("can't copy stored content: %w", err)
+	}
+	if written != storedSize {
+		return LodFileMeta{}, fmt.Errorf("stored content is truncated, %d of %d bytes copied", written, storedSize)
+	}
+	return fileMeta, nil
+}
+
+func compressLodFile(content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("can't create zlib writer: %w", err)
+	}
+	_, err = zw.Write(content)
+	if err != nil {
+		return nil, fmt.Errorf("can't compress content: %w", err)
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, fmt.Errorf("can't compress content: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeLodHeader writes the header and the whole files table, unused slots bytes are aligned to the table end
+func writeLodHeader(w io.Writer, lam *LodArchiveMeta) error {
+	tableSlots := len(lam.Files)
+	if int(lam.TableSize) > tableSlots {
+		tableSlots = int(lam.TableSize)
+	}
+	header := make([]byte, lodHeaderSize+lodFileEntrySize*tableSlots)
+	binary.LittleEndian.PutUint32(header[0:], lodArchiveHeader)
+	binary.LittleEndian.PutUint32(header[4:], uint32(lam.LodType))
+	binary.LittleEndian.PutUint32(header[8:], lam.NumberOfFiles)
+	copy(header[12:lodHeaderSize], lam.Reserved)
+
+	for i, file := range lam.Files {
+		entry := header[lodHeaderSize+lodFileEntrySize*i:]
+		if len(file.NameField) != 0 {
+			copy(entry[:lodFileNameSize], file.NameField)
+		} else {
+			copy(entry[:lodFileNameSize], file.Name)
+		}
+		binary.LittleEndian.PutUint32(entry[16:], file.Offset)
+		binary.LittleEndian.PutUint32(entry[20:], file.OriginalSize)
+		binary.LittleEndian.PutUint32(entry[24:], file.Unknown)
+		binary.LittleEndian.PutUint32(entry[28:], file.CompressedSize)
+	}
+	unusedSlots := header[lodHeaderSize+lodFileEntrySize*len(lam.Files):]
+	if len(lam.UnusedSlots) > len(unusedSlots) {
+		copy(unusedSlots, lam.UnusedSlots[len(lam.UnusedSlots)-len(unusedSlots):])
+	} else {
+		copy(unusedSlots[len(unusedSlots)-len(lam.UnusedSlots):], lam.UnusedSlots)
+	}
+
+	_, err := w.Write(header)
+	if err != nil {
+		return fmt.Errorf("can't write lod archive header: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package lodparse
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+type LodDiffKind string
+
+const (
+	DiffAdded   LodDiffKind = "added"
+	DiffRemoved LodDiffKind = "removed"
+	// DiffResized is reported for files whose original size differs, their content isn't hashed
+	DiffResized LodDiffKind = "resized"
+	// DiffChanged is reported for files of the same original size but with different content
+	DiffChanged LodDiffKind = "changed"
+)
+
+// LodDiffEntry is a single difference between two archives, Name is the name in the new archive if it's there
+type LodDiffEntry struct {
+	Kind    LodDiffKind `json:"kind"`
+	Name    string      `json:"name"`
+	OldSize uint32      `json:"old_size,omitempty"`
+	NewSize uint32      `json:"new_size,omitempty"`
+	OldHash string      `json:"old_sha256,omitempty"`
+	NewHash string      `json:"new_sha256,omitempty"`
+}
+
+func (lde LodDiffEntry) String() string {
+	switch lde.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("+ %s (%d bytes)", lde.Name, lde.NewSize)
+	case DiffRemoved:
+		return fmt.Sprintf("- %s (%d bytes)", lde.Name, lde.OldSize)
+	case DiffResized:
+		return fmt.Sprintf("~ %s (%d -> %d bytes)", lde.Name, lde.OldSize, lde.NewSize)
+	default:
+		return fmt.Sprintf("* %s (%s -> %s)", lde.Name, lde.OldHash, lde.NewHash)
+	}
+}
+
+type LodDiff struct {
+	OldArchive string         `json:"old_archive"`
+	NewArchive string         `json:"new_archive"`
+	Unchanged  int            `json:"unchanged"`
+	Entries    []LodDiffEntry `json:"entries"`
+}
+
+// DiffLodArchives compares files of two archives by name ignoring case and by decompressed content hash.
+// Entries are ordered by name.
+func DiffLodArchives(oldArchive, newArchive *LodArchiveMeta) (*LodDiff, error) {
+	oldReader, oldCloser, err := oldArchive.openReader()
+	if err != nil {
+		return nil, err
+	}
+	defer oldCloser.Close()
+	newReader, newCloser, err := newArchive.openReader()
+	if err != nil {
+		return nil, err
+	}
+	defer newCloser.Close()
+
+	diff := LodDiff{
+		OldArchive: oldArchive.ArchiveFilePath,
+		NewArchive: newArchive.ArchiveFilePath,
+		Entries:    []LodDiffEntry{},
+	}
+
+	newFiles := make(map[string]LodFileMeta, len(newArchive.Files))
+	for _, file := range newArchive.Files {
+		if _, ok := newFiles[foldName(file.Name)]; !ok {
+			newFiles[foldName(file.Name)] = file
+		}
+	}
+	oldFiles := make(map[string]bool, len(oldArchive.Files))
+	for _, oldFile := range oldArchive.Files {
+		folded := foldName(oldFile.Name)
+		if oldFiles[folded] {
+			continue
+		}
+		oldFiles[folded] = true
+
+		newFile, ok := newFiles[folded]
+		if !ok {
+			diff.Entries = append(diff.Entries, LodDiffEntry{Kind: DiffRemoved, Name: oldFile.Name, OldSize: oldFile.OriginalSize})
+			continue
+		}
+		entry, err := diffLodFiles(oldArchive, oldReader, oldFile, newArchive, newReader, newFile)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			diff.Unchanged++
+			continue
+		}
+		diff.Entries = append(diff.Entries, *entry)
+	}
+	for folded, newFile := range newFiles {
+		if !oldFiles[folded] {
+			diff.Entries = append(diff.Entries, LodDiffEntry{Kind: DiffAdded, Name: newFile.Name, NewSize: newFile.OriginalSize})
+		}
+	}
+
+	sort.Slice(diff.Entries, func(i, j int) bool {
+		return foldName(diff.Entries[i].Name) < foldName(diff.Entries[j].Name)
+	})
+	return &diff, nil
+}
+
+// diffLodFiles returns nil when files have the same content
+func diffLodFiles(oldArchive *LodArchiveMeta, oldReader io.ReaderAt, oldFile LodFileMeta, newArchive *LodArchiveMeta, newReader io.ReaderAt, newFile LodFileMeta) (*LodDiffEntry, error) {
+	entry := LodDiffEntry{
+		Name:    newFile.Name,
+		OldSize: oldFile.OriginalSize,
+		NewSize: newFile.OriginalSize,
+	}
+	if oldFile.OriginalSize != newFile.OriginalSize {
+		entry.Kind = DiffResized
+		return &entry, nil
+	}
+
+	var err error
+	entry.OldHash, err = oldArchive.contentSHA256(oldReader, oldFile)
+	if err != nil {
+		return nil, err
+	}
+	entry.NewHash, err = newArchive.contentSHA256(newReader, newFile)
+	if err != nil {
+		return nil, err
+	}
+	if entry.OldHash == entry.NewHash {
+		return nil, nil
+	}
+	entry.Kind = DiffChanged
+	return &entry, nil
+}
@@ -0,0 +1,155 @@
+package lodparse
+
+import (
+	"io"
+	"sort"
+)
+
+// ArchivedFile is a file of a particular archive, SHA256 is the hash of its original content
+type ArchivedFile struct {
+	Archive string `json:"archive"`
+	Name    string `json:"name"`
+	Size    uint32 `json:"size"`
+	SHA256  string `json:"sha256"`
+}
+
+// DuplicateGroup is a set of files with identical original content, possibly from the same archive
+type DuplicateGroup struct {
+	SHA256 string         `json:"sha256"`
+	Size   uint32         `json:"size"`
+	Files  []ArchivedFile `json:"files"`
+}
+
+// WastedBytes is the original size of all copies except one
+func (dg DuplicateGroup) WastedBytes() int64 {
+	return int64(dg.Size) * int64(len(dg.Files)-1)
+}
+
+// NameConflict is a name used in several archives for different content, Files holds one file per archive
+type NameConflict struct {
+	Name  string         `json:"name"`
+	Files []ArchivedFile `json:"files"`
+}
+
+type DuplicatesReport struct {
+	Archives    []string         `json:"archives"`
+	Duplicates  []DuplicateGroup `json:"duplicates"`
+	Conflicts   []NameConflict   `json:"conflicts"`
+	WastedBytes int64            `json:"wasted_bytes"`
+}
+
+// FindDuplicates hashes decompressed content of files across archives and groups identical ones.
+// Names are compared ignoring case, within an archive only the first of case colliding files is used for conflicts.
+// Only files sharing a size or a name with another file are hashed. Groups are ordered by wasted bytes, conflicts by name.
+func FindDuplicates(archives []*LodArchiveMeta) (*DuplicatesReport, error) {
+	report := DuplicatesReport{
+		Archives:   make([]string, 0, len(archives)),
+		Duplicates: []DuplicateGroup{},
+		Conflicts:  []NameConflict{},
+	}
+
+	sizes := make(map[uint32]int)
+	names := make(map[string]int)
+	for _, lam := range archives {
+		report.Archives = append(report.Archives, lam.ArchiveFilePath)
+		if lam.foldedIndexes == nil {
+			lam.indexFiles()
+		}
+		for folded := range lam.foldedIndexes {
+			names[folded]++
+		}
+		for _, file := range lam.Files {
+			sizes[file.OriginalSize]++
+		}
+	}
+
+	byHash := make(map[string][]ArchivedFile)
+	byName := make(map[string][]ArchivedFile)
+	for _, lam := range archives {
+		err := hashCandidates(lam, sizes, names, byHash, byName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for sha, files := range byHash {
+		if len(files) < 2 {
+			continue
+		}
+		group := DuplicateGroup{SHA256: sha, Size: files[0].Size, Files: files}
+		report.Duplicates = append(report.Duplicates, group)
+		report.WastedBytes += group.WastedBytes()
+	}
+	sort.Slice(report.Duplicates, func(i, j int) bool {
+		wi, wj := report.Duplicates[i].WastedBytes(), report.Duplicates[j].WastedBytes()
+		if wi != wj {
+			return wi > wj
+		}
+		return report.Duplicates[i].SHA256 < report.Duplicates[j].SHA256
+	})
+
+	for _, files := range byName {
+		if len(files) < 2 || sameContent(files) {
+			continue
+		}
+		report.Conflicts = append(report.Conflicts, NameConflict{Name: files[0].Name, Files: files})
+	}
+	sort.Slice(report.Conflicts, func(i, j int) bool {
+		return foldName(report.Conflicts[i].Name) < foldName(report.Conflicts[j].Name)
+	})
+
+	return &report, nil
+}
+
+// hashCandidates hashes files sharing a size or a name with another file,
+// for names only the first of case colliding files is used, the same one GetFile returns
+func hashCandidates(lam *LodArchiveMeta, sizes map[uint32]int, names map[string]int, byHash, byName map[string][]ArchivedFile) error {
+	lodReader, lodCloser, err := lam.openReader()
+	if err != nil {
+		return err
+	}
+	defer lodCloser.Close()
+
+	for fi, file := range lam.Files {
+		folded := foldName(file.Name)
+		isFirst := lam.foldedIndexes[folded] == fi
+		nameShared := isFirst && names[folded] > 1
+		if sizes[file.OriginalSize] < 2 && !nameShared {
+			continue
+		}
+
+		af, err := archivedFile(lam, lodReader, file)
+		if err != nil {
+			return err
+		}
+		if sizes[file.OriginalSize] > 1 {
+			byHash[af.SHA256] = append(byHash[af.SHA256], af)
+		}
+		if nameShared {
+			byName[folded] = append(byName[folded], af)
+		}
+	}
+	return nil
+}
+
+func archivedFile(lam *LodArchiveMeta, lodReader io.ReaderAt, file LodFileMeta) (ArchivedFile, error) {
+	sha, err := lam.contentSHA256(lodReader, file)
+	if err != nil {
+		return ArchivedFile{}, err
+	}
+	return ArchivedFile{
+		Archive: lam.ArchiveFilePath,
+		Name:    file.Name,
+		Size:    file.OriginalSize,
+		SHA256:  sha,
+	}, nil
+}
+
+func sameContent(files []ArchivedFile) bool {
+	for _, file := range files[1:] {
+		if file.SHA256 != files[0].SHA256 {
+			return false
+		}
+	}
+	return true
+}
@@ -1,35 +1,136 @@
 package lodparse
 
 import (
-	"bytes"
-	"compress/zlib"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
 const defaultConcurrencyLevel = 4
 
+// ExtractOptions configures ExtractLodFilesContext
+type ExtractOptions struct {
+	// ConcurrencyLevel is the number of goroutines extracting files, 0 means default level
+	ConcurrencyLevel int
+	// ContinueOnError keeps extracting remaining files after a file fails
+	ContinueOnError bool
+	// Progress is called after each extracted file, calls are never concurrent
+	Progress func(ExtractProgress)
+	// Filter selects files to extract, all files are extracted when it's nil.
+	// Nothing is extracted and no error is returned when it matches no files.
+	Filter func(LodFileMeta) bool
+	// Converters are applied instead of writing raw files, they are chosen by the file extension
+	// with the leading dot, case is ignored. Files without a converter are written raw.
+	Converters map[string]LodFileConverter
+	// KeepRaw writes raw files alongside converted ones
+	KeepRaw bool
+}
+
+// LodFileConverter writes the converted original content of a file into dstDir
+type LodFileConverter func(file LodFileMeta, content io.Reader, dstDir string) error
+
+// ExtractProgress is reported to ExtractOptions.Progress after each extracted file
+type ExtractProgress struct {
+	File         LodFileMeta
+	FilesDone    int
+	FilesTotal   int
+	BytesWritten int64
+}
+
+// ExtractFileError is an error of a single lod file extraction
+type ExtractFileError struct {
+	File LodFileMeta
+	Err  error
+}
+
+func (efe *ExtractFileError) Error() string {
+	return fmt.Sprintf("can't extract lod file(%s): %v", efe.File.Name, efe.Err)
+}
+
+func (efe *ExtractFileError) Unwrap() error {
+	return efe.Err
+}
+
+// ExtractErrors aggregates errors of all files that failed during extraction
+type ExtractErrors []*ExtractFileError
+
+func (ee ExtractErrors) Error() string {
+	msgs := make([]string, 0, len(ee))
+	for _, e := range ee {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("%d lod files failed: %s", len(ee), strings.Join(msgs, "; "))
+}
+
 func ExtractLodFiles(lodArchive *LodArchiveMeta, dstDir string, concurrencyLevel int) error {
+	return ExtractLodFilesContext(context.Background(), lodArchive, dstDir, ExtractOptions{
+		ConcurrencyLevel: concurrencyLevel,
+	})
+}
+
+// ExtractLodFilesContext extracts lod archive files into dstDir.
+// Failed files are returned as ExtractErrors, ctx.Err() is returned when extraction was cancelled without failures.
+func ExtractLodFilesContext(ctx context.Context, lodArchive *LodArchiveMeta, dstDir string, opts ExtractOptions) error {
+	files := lodArchive.Files
+	if opts.Filter != nil {
+		files = lodArchive.Filter(opts.Filter)
+		if len(files) == 0 {
+			return nil
+		}
+	}
+	concurrencyLevel := opts.ConcurrencyLevel
 	if concurrencyLevel == 0 {
 		concurrencyLevel = defaultConcurrencyLevel
 	}
-	if concurrencyLevel > int(lodArchive.NumberOfFiles) {
-		concurrencyLevel = int(lodArchive.NumberOfFiles)
+	if concurrencyLevel > len(files) {
+		concurrencyLevel = len(files)
 		if concurrencyLevel == 0 {
 			return errors.New("zero concurrency level in ExtractLodFiles due to empty lod archive")
 		}
 	}
 
+	lodReader, lodCloser, err := lodArchive.openReader()
+	if err != nil {
+		return err
+	}
+	defer lodCloser.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		errs     ExtractErrors
+		progress = ExtractProgress{FilesTotal: len(files)}
+	)
+	report := func(file LodFileMeta, written int64, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, &ExtractFileError{File: file, Err: err})
+			if !opts.ContinueOnError {
+				cancel()
+			}
+			return
+		}
+		progress.File = file
+		progress.FilesDone++
+		progress.BytesWritten += written
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(concurrencyLevel)
 
-	batchSize := int(lodArchive.NumberOfFiles) / concurrencyLevel
-	remainingFiles := int(lodArchive.NumberOfFiles) % concurrencyLevel
+	batchSize := len(files) / concurrencyLevel
+	remainingFiles := len(files) % concurrencyLevel
 	for l := 1; l <= concurrencyLevel; l++ {
 		start := batchSize * (l-1)
 		end   := batchSize * l
@@ -39,65 +140,109 @@ func ExtractLodFiles(lodArchive *LodArchiveMeta, dstDir string, concurrencyLevel
 
 		go func() {
 			defer wg.Done()
-			lodFileReader, err := os.Open(lodArchive.ArchiveFilePath)
-			if err != nil {
-				panic(fmt.Errorf("can't open lod archive(%s): %w", lodArchive.ArchiveFilePath, err))
-			}
-			defer lodFileReader.Close()
-
-			for _, file := range lodArchive.Files[start:end] {
-				err = ExtractFile(file, lodFileReader, dstDir)
-				if err != nil {
-					panic(fmt.Errorf("can't extract lod archive(%s) file(%s): %w", lodArchive.ArchiveFilePath, file.Name, err))
+			for _, file := range files[start:end] {
+				if ctx.Err() != nil {
+					return
 				}
+				written, err := lodArchive.extractOrConvertFile(file, lodReader, dstDir, opts)
+				report(file, written, err)
 			}
 		}()
 	}
 	wg.Wait()
 
-	return nil
+	if len(errs) != 0 {
+		return errs
+	}
+	// cancel is only called internally on failure, so a cancelled ctx here comes from the caller
+	return ctx.Err()
 }
 
-func ExtractFile(file LodFileMeta, lodFileReader *os.File, dstDir string) error {
-	var fsize uint32
-	if file.IsCompressed() {
-		fsize = file.CompressedSize
-	} else {
-		fsize = file.OriginalSize
+// ExtractFile streams a single file from the archive read by lodReader into dstDir.
+// Only small buffers are held in memory, so files of any size can be extracted concurrently.
+func ExtractFile(file LodFileMeta, lodReader io.ReaderAt, dstDir string) error {
+	_, err := extractFileAt(file, lodReader, dstDir)
+	return err
+}
+
+// extractOrConvertFile returns the number of written raw bytes, converted output isn't counted
+func (lam *LodArchiveMeta) extractOrConvertFile(file LodFileMeta, lodReader io.ReaderAt, dstDir string, opts ExtractOptions) (int64, error) {
+	convert := findConverter(opts.Converters, file.Name)
+	if convert == nil {
+		return lam.extractFile(file, lodReader, dstDir)
 	}
 
-	_, err := lodFileReader.Seek(int64(file.Offset), io.SeekStart)
-	if err != nil {
-		return fmt.Errorf("can't seek on lod archive: %w", err)
+	var written int64
+	if opts.KeepRaw {
+		var err error
+		written, err = lam.extractFile(file, lodReader, dstDir)
+		if err != nil {
+			return written, err
+		}
 	}
 
-	fb := make([]byte, fsize)
-	_, err = lodFileReader.Read(fb)
+	content, err := lam.openContent(lodReader, file)
 	if err != nil {
-		return fmt.Errorf("can't read lod archive: %w", err)
+		return written, err
 	}
-	var fbr = ioutil.NopCloser(bytes.NewReader(fb))
-	defer fbr.Close()
+	defer content.Close()
+	err = convert(file, content, dstDir)
+	if err != nil {
+		return written, fmt.Errorf("can't convert lod file: %w", err)
+	}
+	return written, nil
+}
 
-	if file.IsCompressed() {
-		fbr, err = zlib.NewReader(fbr)
-		if err != nil {
-			return fmt.Errorf("can't create zlib reader during decompressng lod file(%s): %w", file.Name, err)
+func findConverter(converters map[string]LodFileConverter, name string) LodFileConverter {
+	if len(converters) == 0 {
+		return nil
+	}
+	ext := foldName(filepath.Ext(name))
+	for convExt, convert := range converters {
+		if foldName(convExt) == ext {
+			return convert
 		}
 	}
+	return nil
+}
+
+func extractFileAt(file LodFileMeta, lodReader io.ReaderAt, dstDir string) (int64, error) {
+	content, err := openFileAt(lodReader, file)
+	if err != nil {
+		return 0, err
+	}
+	defer content.Close()
+
+	return writeFile(file, content, dstDir)
+}
+
+// extractFile extracts pending content of added and replaced files as well
+func (lam *LodArchiveMeta) extractFile(file LodFileMeta, lodReader io.ReaderAt, dstDir string) (int64, error) {
+	content, err := lam.openContent(lodReader, file)
+	if err != nil {
+		return 0, err
+	}
+	defer content.Close()
 
-	return writeFile(file, fbr, dstDir)
+	return writeFile(file, content, dstDir)
 }
 
-func writeFile(fileMeta LodFileMeta, bufReader io.Reader, dstDir string) error {
-	file, err := os.Create(filepath.Join(dstDir, fileMeta.Name))
+// writeFile removes the written file when content can't be copied completely
+func writeFile(fileMeta LodFileMeta, bufReader io.Reader, dstDir string) (int64, error) {
+	dstPath := filepath.Join(dstDir, fileMeta.Name)
+	file, err := os.Create(dstPath)
 	if err != nil {
-		return fmt.Errorf("can't create lod file: %w", err)
+		return 0, fmt.Errorf("can't create lod file: %w", err)
+	}
+	written, err := io.Copy(file, bufReader)
+	if err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
 	}
-	defer file.Close()
-	_, err = io.Copy(file, bufReader)
 	if err != nil {
-		return fmt.Errorf("can't write lod file: %w", err)
+		os.Remove(dstPath)
+		return written, fmt.Errorf("can't write lod file: %w", err)
 	}
-	return nil
+	return written, nil
 }
@@ -0,0 +1,192 @@
+package lodparse
+
+import (
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// Open returns a reader of the original (decompressed) content of the named file
+func (lam *LodArchiveMeta) Open(name string) (io.ReadCloser, error) {
+	file, err := lam.GetFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't get lod file(%s): %w", name, err)
+	}
+	return lam.openFile(file)
+}
+
+// ReadFile returns the original (decompressed) content of the named file
+func (lam *LodArchiveMeta) ReadFile(name string) ([]byte, error) {
+	file, err := lam.GetFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't get lod file(%s): %w", name, err)
+	}
+	return lam.readFile(file)
+}
+
+// maxPreallocatedSize limits memory allocated up front for compressed files,
+// their original size is taken from the table and is known to be right only after decompression
+const maxPreallocatedSize = 16 << 20
+
+func (lam *LodArchiveMeta) readFile(file LodFileMeta) ([]byte, error) {
+	if _, ok := lam.pending[file.Name]; ok {
+		content, err := lam.openContent(nil, file)
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+		return io.ReadAll(content)
+	}
+
+	lodReader, lodCloser, err := lam.openReader()
+	if err != nil {
+		return nil, err
+	}
+	defer lodCloser.Close()
+
+	archiveSize, err := lam.archiveSize(lodReader)
+	if err != nil {
+		return nil, err
+	}
+	if int64(file.Offset)+int64(file.storedSize()) > archiveSize {
+		return nil, fmt.Errorf("lod file(%s) content is outside of lod archive", file.Name)
+	}
+	preallocatedSize := file.OriginalSize
+	if file.IsCompressed() && preallocatedSize > maxPreallocatedSize {
+		preallocatedSize = maxPreallocatedSize
+	}
+
+	content, err := openFileAt(lodReader, file)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	fb, err := readAllPreallocated(content, preallocatedSize)
+	if err != nil {
+		return nil, fmt.Errorf("can't read lod file(%s): %w", file.Name, err)
+	}
+	return fb, nil
+}
+
+// readAllPreallocated is io.ReadAll starting with size bytes of capacity, one byte more is left to read EOF without growing
+func readAllPreallocated(r io.Reader, size uint32) ([]byte, error) {
+	b := make([]byte, 0, int64(size)+1)
+	for {
+		if len(b) == cap(b) {
+			b = append(b, 0)[:len(b)]
+		}
+		n, err := r.Read(b[len(b):cap(b)])
+		b = b[:len(b)+n]
+		if err == io.EOF {
+			return b, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (lam *LodArchiveMeta) openFile(file LodFileMeta) (io.ReadCloser, error) {
+	if _, ok := lam.pending[file.Name]; ok {
+		return lam.openContent(nil, file)
+	}
+
+	lodReader, lodCloser, err := lam.openReader()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := openFileAt(lodReader, file)
+	if err != nil {
+		lodCloser.Close()
+		return nil, err
+	}
+	content.closers = append(content.closers, lodCloser)
+	return content, nil
+}
+
+// openContent returns a reader of the original content of the file. Content of added and replaced files
+// is read from their sources until the archive is saved, content of other files from the archive read by lodReader.
+func (lam *LodArchiveMeta) openContent(lodReader io.ReaderAt, file LodFileMeta) (io.ReadCloser, error) {
+	if source, ok := lam.pending[file.Name]; ok {
+		content, err := source.Open()
+		if err != nil {
+			return nil, fmt.Errorf("can't open source of lod file(%s): %w", file.Name, err)
+		}
+		return &lodFileContent{Reader: newSizeCheckedReader(content, file), closers: []io.Closer{content}}, nil
+	}
+
+	content, err := openFileAt(lodReader, file)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// openFileAt returns a reader of the file content stored in the lod archive read by lodReader.
+// The reader fails when the content turns out to be shorter or longer than the original size.
+func openFileAt(lodReader io.ReaderAt, file LodFileMeta) (*lodFileContent, error) {
+	var content io.Reader = io.NewSectionReader(lodReader, int64(file.Offset), int64(file.storedSize()))
+	if file.IsCompressed() {
+		zr, err := zlib.NewReader(content)
+		if err != nil {
+			return nil, fmt.Errorf("can't create zlib reader during decompressng lod file(%s): %w", file.Name, err)
+		}
+		return &lodFileContent{Reader: newSizeCheckedReader(zr, file), closers: []io.Closer{zr}}, nil
+	}
+	return &lodFileContent{Reader: newSizeCheckedReader(content, file)}, nil
+}
+
+type sizeCheckedReader struct {
+	r         io.Reader
+	name      string
+	remaining int64
+}
+
+func newSizeCheckedReader(r io.Reader, file LodFileMeta) *sizeCheckedReader {
+	return &sizeCheckedReader{r: r, name: file.Name, remaining: int64(file.OriginalSize)}
+}
+
+func (scr *sizeCheckedReader) Read(p []byte) (int, error) {
+	if scr.remaining == 0 {
+		// one more byte is read to be sure the content ends here, it also lets zlib verify the checksum
+		var extra [1]byte
+		n, err := io.ReadAtLeast(scr.r, extra[:], 1)
+		if n != 0 {
+			return 0, fmt.Errorf("lod file(%s) content is longer than its original size", scr.name)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > scr.remaining {
+		p = p[:scr.remaining]
+	}
+	n, err := scr.r.Read(p)
+	scr.remaining -= int64(n)
+	if err == io.EOF {
+		if scr.remaining != 0 {
+			return n, fmt.Errorf("lod file(%s) content is %d bytes shorter than its original size: %w", scr.name, scr.remaining, io.ErrUnexpectedEOF)
+		}
+		err = nil
+	}
+	return n, err
+}
+
+type lodFileContent struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (lfc *lodFileContent) Close() error {
+	var firstErr error
+	for _, c := range lfc.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,82 @@
+package lodparse
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// LodFileFilter selects files by name and size, a file must satisfy all set criteria to match.
+// Names, patterns and extensions are compared ignoring case.
+type LodFileFilter struct {
+	// Include patterns in path.Match syntax, a file matches if it matches any of them
+	Include []string
+	// Exclude patterns in path.Match syntax, a file doesn't match if it matches any of them
+	Exclude []string
+	// Extensions with or without the leading dot
+	Extensions []string
+	Names      []string
+	// MinSize and MaxSize limit the original size, zero MaxSize means no limit
+	MinSize uint32
+	MaxSize uint32
+}
+
+// Validate checks patterns syntax, Match treats malformed patterns as not matching
+func (lff LodFileFilter) Validate() error {
+	for _, pattern := range append(append([]string{}, lff.Include...), lff.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern(%s): %w", pattern, err)
+		}
+	}
+	if lff.MaxSize != 0 && lff.MinSize > lff.MaxSize {
+		return fmt.Errorf("min size %d is greater than max size %d", lff.MinSize, lff.MaxSize)
+	}
+	return nil
+}
+
+func (lff LodFileFilter) Match(file LodFileMeta) bool {
+	name := foldName(file.Name)
+
+	if len(lff.Include) != 0 && !matchAny(lff.Include, name) {
+		return false
+	}
+	if matchAny(lff.Exclude, name) {
+		return false
+	}
+	if len(lff.Extensions) != 0 {
+		ext := strings.TrimPrefix(path.Ext(name), ".")
+		found := false
+		for _, e := range lff.Extensions {
+			if foldName(strings.TrimPrefix(e, ".")) == ext {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(lff.Names) != 0 {
+		found := false
+		for _, n := range lff.Names {
+			if foldName(n) == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return file.OriginalSize >= lff.MinSize && (lff.MaxSize == 0 || file.OriginalSize <= lff.MaxSize)
+}
+
+func matchAny(patterns []string, foldedName string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(foldName(pattern), foldedName); matched {
+			return true
+		}
+	}
+	return false
+}
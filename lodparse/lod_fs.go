@@ -0,0 +1,170 @@
+package lodparse
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// FS returns a read-only file system with all archive files placed in its root directory.
+// Compressed files are inflated on read.
+func (lam *LodArchiveMeta) FS() fs.FS {
+	if lam.filesIndexes == nil {
+		lam.indexFiles()
+	}
+	return lodFS{lam: lam}
+}
+
+type lodFS struct {
+	lam *LodArchiveMeta
+}
+
+func (lfs lodFS) Open(name string) (fs.File, error) {
+	if name == "." {
+		return &lodDir{entries: lfs.sortedDirEntries()}, nil
+	}
+	fileMeta, err := lfs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := lfs.lam.openFile(fileMeta)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &lodFile{meta: fileMeta, content: content}, nil
+}
+
+func (lfs lodFS) Stat(name string) (fs.FileInfo, error) {
+	if name == "." {
+		return lodDirInfo{}, nil
+	}
+	fileMeta, err := lfs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return lodFileInfo{meta: fileMeta}, nil
+}
+
+func (lfs lodFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		if _, err := lfs.lookup("readdir", name); err != nil {
+			return nil, err
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return lfs.sortedDirEntries(), nil
+}
+
+func (lfs lodFS) ReadFile(name string) ([]byte, error) {
+	if name == "." {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	fileMeta, err := lfs.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := lfs.lam.readFile(fileMeta)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return content, nil
+}
+
+func (lfs lodFS) lookup(op, name string) (LodFileMeta, error) {
+	if !fs.ValidPath(name) {
+		return LodFileMeta{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	fileMeta, err := lfs.lam.GetFile(name)
+	if errors.Is(err, errNoSuchFile) {
+		return LodFileMeta{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		return LodFileMeta{}, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return fileMeta, nil
+}
+
+func (lfs lodFS) sortedDirEntries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(lfs.lam.Files))
+	for _, file := range lfs.lam.Files {
+		entries = append(entries, fs.FileInfoToDirEntry(lodFileInfo{meta: file}))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+type lodFile struct {
+	meta    LodFileMeta
+	content io.ReadCloser
+}
+
+func (lf *lodFile) Stat() (fs.FileInfo, error) {
+	return lodFileInfo{meta: lf.meta}, nil
+}
+
+func (lf *lodFile) Read(b []byte) (int, error) {
+	return lf.content.Read(b)
+}
+
+func (lf *lodFile) Close() error {
+	return lf.content.Close()
+}
+
+type lodFileInfo struct {
+	meta LodFileMeta
+}
+
+func (lfi lodFileInfo) Name() string       { return lfi.meta.Name }
+func (lfi lodFileInfo) Size() int64        { return int64(lfi.meta.OriginalSize) }
+func (lfi lodFileInfo) Mode() fs.FileMode  { return 0444 }
+func (lfi lodFileInfo) ModTime() time.Time { return time.Time{} }
+func (lfi lodFileInfo) IsDir() bool        { return false }
+func (lfi lodFileInfo) Sys() interface{}   { return lfi.meta }
+
+type lodDirInfo struct{}
+
+func (lodDirInfo) Name() string       { return "." }
+func (lodDirInfo) Size() int64        { return 0 }
+func (lodDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (lodDirInfo) ModTime() time.Time { return time.Time{} }
+func (lodDirInfo) IsDir() bool        { return true }
+func (lodDirInfo) Sys() interface{}   { return nil }
+
+type lodDir struct {
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (ld *lodDir) Stat() (fs.FileInfo, error) {
+	return lodDirInfo{}, nil
+}
+
+func (ld *lodDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+func (ld *lodDir) Close() error {
+	return nil
+}
+
+func (ld *lodDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := ld.entries[ld.offset:]
+	if n <= 0 {
+		ld.offset = len(ld.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	ld.offset += n
+	return remaining[:n], nil
+}
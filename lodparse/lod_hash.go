@@ -0,0 +1,55 @@
+package lodparse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ComputeHashes sets SHA256 and CRC32 of the original (decompressed) content of each file
+func (lam *LodArchiveMeta) ComputeHashes() error {
+	lodReader, lodCloser, err := lam.openReader()
+	if err != nil {
+		return err
+	}
+	defer lodCloser.Close()
+
+	for i, file := range lam.Files {
+		lam.Files[i].SHA256, lam.Files[i].CRC32, err = lam.contentHashes(lodReader, file)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentSHA256 returns hex encoded SHA-256 of the original file content. Stored hashes are used only for metas
+// of manifests, hashes of other metas may be left from an older archive content, like ones loaded from json.
+func (lam *LodArchiveMeta) contentSHA256(lodReader io.ReaderAt, file LodFileMeta) (string, error) {
+	if lam.hashesOnly {
+		if file.SHA256 == "" {
+			return "", fmt.Errorf("manifest has no hash of lod file(%s)", file.Name)
+		}
+		return file.SHA256, nil
+	}
+	sha, _, err := lam.contentHashes(lodReader, file)
+	return sha, err
+}
+
+func (lam *LodArchiveMeta) contentHashes(lodReader io.ReaderAt, file LodFileMeta) (sha string, crc string, err error) {
+	content, err := lam.openContent(lodReader, file)
+	if err != nil {
+		return "", "", err
+	}
+	defer content.Close()
+
+	shaHash := sha256.New()
+	crcHash := crc32.NewIEEE()
+	_, err = io.Copy(io.MultiWriter(shaHash, crcHash), content)
+	if err != nil {
+		return "", "", fmt.Errorf("can't hash lod file(%s): %w", file.Name, err)
+	}
+	return hex.EncodeToString(shaHash.Sum(nil)), hex.EncodeToString(crcHash.Sum(nil)), nil
+}
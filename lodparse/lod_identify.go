@@ -0,0 +1,144 @@
+package lodparse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LodProduct is a game release or a mod an archive comes from
+type LodProduct string
+
+const (
+	ProductUnknown LodProduct = "unknown"
+	// ProductRoE is The Restoration of Erathia
+	ProductRoE LodProduct = "roe"
+	// ProductAB is Armageddon's Blade, it added the Conflux town
+	ProductAB LodProduct = "ab"
+	// ProductSoD is The Shadow of Death, it added combination artifacts.
+	// Archives of the Complete edition have the same entries, so they are identified as SoD too.
+	ProductSoD  LodProduct = "sod"
+	ProductHotA LodProduct = "hota"
+)
+
+// LodFingerprint tells the product of archives matching all of its set criteria, a fingerprint without criteria never matches
+type LodFingerprint struct {
+	Product LodProduct
+	// LodType is only supporting evidence, it's reported when a matched archive has the type, but it doesn't affect matching
+	LodType LodArchiveType
+	// AnyOf matches archives having at least one of the named files, names are compared ignoring case
+	AnyOf []string
+	// SHA256 matches archives having all the named files with the original content of the hex encoded hashes
+	SHA256 map[string]string
+}
+
+// DefaultLodFingerprints are checked from the first to the last by Identify, so later releases go first.
+// Game releases have entries of both H3bitmap.lod and H3sprite.lod listed, so either archive is identified.
+// Of HotA only HotA_lng.lod is known, HotA.lod is identified as unknown. The Complete edition and WoG
+// aren't identified, use IdentifyWith with entries or content hashes of your copy to tell them.
+var DefaultLodFingerprints = []LodFingerprint{
+	// HotA campaigns of HotA_lng.lod, the archive of the lodparse test data has type 0x1F4
+	{Product: ProductHotA, LodType: 0x1F4, AnyOf: []string{"H3Horn.h3c", "H1Roger.h3c", "H2Terror.h3c"}},
+	// the campaign sets screen of H3bitmap.lod and its SoD button and map objects of combination artifacts of H3sprite.lod
+	{Product: ProductSoD, AnyOf: []string{"CampBkX2.pcx", "CSSsod.def", "AVA0129.def", "AVA0130.def", "AVA0131.def"}},
+	// Conflux town screen of H3bitmap.lod and Conflux creatures of H3sprite.lod
+	{Product: ProductAB, AnyOf: []string{"TBELBACK.pcx", "CPIXIE.def", "CPHX.def"}},
+	// Castle town screen of H3bitmap.lod and Castle creatures of H3sprite.lod present since the first release
+	{Product: ProductRoE, AnyOf: []string{"TBCSBACK.pcx", "CPKMAN.def", "CANGEL.def"}},
+}
+
+// LodIdentity is the result of an archive identification, Evidence lists what the product was told by
+type LodIdentity struct {
+	Product  LodProduct     `json:"product"`
+	LodType  LodArchiveType `json:"lod_type"`
+	Evidence []string       `json:"evidence,omitempty"`
+}
+
+func (li LodIdentity) String() string {
+	if len(li.Evidence) == 0 {
+		return fmt.Sprintf("%s (lod type %s)", li.Product, li.LodType)
+	}
+	return fmt.Sprintf("%s (%s)", li.Product, strings.Join(li.Evidence, ", "))
+}
+
+// Identify tells the product of the archive by DefaultLodFingerprints
+func (lam *LodArchiveMeta) Identify() (LodIdentity, error) {
+	return lam.IdentifyWith(DefaultLodFingerprints)
+}
+
+// IdentifyWith tells the product of the archive by the first matching fingerprint.
+// Files are read only to check content hashes of fingerprints having them.
+func (lam *LodArchiveMeta) IdentifyWith(fingerprints []LodFingerprint) (LodIdentity, error) {
+	for _, fp := range fingerprints {
+		evidence, err := lam.matchFingerprint(fp)
+		if err != nil {
+			return LodIdentity{}, err
+		}
+		if evidence != nil {
+			return LodIdentity{Product: fp.Product, LodType: lam.LodType, Evidence: evidence}, nil
+		}
+	}
+	return LodIdentity{Product: ProductUnknown, LodType: lam.LodType}, nil
+}
+
+// matchFingerprint returns nil evidence when the fingerprint doesn't match
+func (lam *LodArchiveMeta) matchFingerprint(fp LodFingerprint) ([]string, error) {
+	if len(fp.AnyOf) == 0 && len(fp.SHA256) == 0 {
+		return nil, nil
+	}
+
+	evidence := []string{}
+
+	if len(fp.AnyOf) != 0 {
+		found := ""
+		for _, name := range fp.AnyOf {
+			if file, err := lam.GetFile(name); err == nil {
+				found = file.Name
+				break
+			}
+		}
+		if found == "" {
+			return nil, nil
+		}
+		evidence = append(evidence, "has "+found)
+	}
+
+	names := make([]string, 0, len(fp.SHA256))
+	for name := range fp.SHA256 {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file, err := lam.GetFile(name)
+		if err != nil {
+			return nil, nil
+		}
+		matched, err := lam.contentMatches(file, fp.SHA256[name])
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			return nil, nil
+		}
+		evidence = append(evidence, "content of "+file.Name)
+	}
+
+	if fp.LodType != 0 && lam.LodType == fp.LodType {
+		evidence = append(evidence, "lod type "+fp.LodType.String())
+	}
+	return evidence, nil
+}
+
+func (lam *LodArchiveMeta) contentMatches(file LodFileMeta, sha string) (bool, error) {
+	lodReader, lodCloser, err := lam.openReader()
+	if err != nil {
+		return false, err
+	}
+	defer lodCloser.Close()
+
+	fileSHA, err := lam.contentSHA256(lodReader, file)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(fileSHA, sha), nil
+}
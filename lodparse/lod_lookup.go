@@ -0,0 +1,51 @@
+package lodparse
+
+import (
+	"path"
+	"sort"
+)
+
+// Glob returns files whose names match the pattern ignoring case, pattern syntax is the same as in path.Match
+func (lam *LodArchiveMeta) Glob(pattern string) ([]LodFileMeta, error) {
+	foldedPattern := foldName(pattern)
+	if _, err := path.Match(foldedPattern, ""); err != nil {
+		return nil, err
+	}
+
+	return lam.Filter(func(file LodFileMeta) bool {
+		matched, _ := path.Match(foldedPattern, foldName(file.Name))
+		return matched
+	}), nil
+}
+
+// Filter returns files for which keep returns true, in archive order
+func (lam *LodArchiveMeta) Filter(keep func(LodFileMeta) bool) []LodFileMeta {
+	var files []LodFileMeta
+	for _, file := range lam.Files {
+		if keep(file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// CaseCollisions returns groups of files whose names are equal when case is ignored.
+// GetFile resolves such names to the first file of a group unless the name matches exactly.
+func (lam *LodArchiveMeta) CaseCollisions() [][]LodFileMeta {
+	groups := make(map[string][]LodFileMeta)
+	for _, file := range lam.Files {
+		folded := foldName(file.Name)
+		groups[folded] = append(groups[folded], file)
+	}
+
+	var collisions [][]LodFileMeta
+	for _, group := range groups {
+		if len(group) > 1 {
+			collisions = append(collisions, group)
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return foldName(collisions[i][0].Name) < foldName(collisions[j][0].Name)
+	})
+	return collisions
+}
@@ -0,0 +1,185 @@
+package lodparse
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LodManifest describes a published lod archive, so an installed archive can be checked against it
+type LodManifest struct {
+	Archive string            `json:"archive"`
+	LodType LodArchiveType    `json:"lod_type"`
+	Size    int64             `json:"size"`
+	SHA256  string            `json:"sha256"`
+	Files   []LodManifestFile `json:"files"`
+	// Signature is base64 encoded ed25519 signature of the manifest JSON without the signature itself
+	Signature string `json:"signature,omitempty"`
+}
+
+type LodManifestFile struct {
+	Name         string `json:"name"`
+	OriginalSize uint32 `json:"original_size"`
+	SHA256       string `json:"sha256"`
+	CRC32        string `json:"crc32"`
+}
+
+var ErrManifestNotSigned = errors.New("manifest is not signed")
+
+// NewLodManifest hashes the whole archive and the original content of each file, the archive must have no unsaved changes
+func NewLodManifest(lam *LodArchiveMeta) (*LodManifest, error) {
+	if lam.HasChanges() {
+		return nil, ErrUnsavedChanges
+	}
+	err := lam.ComputeHashes()
+	if err != nil {
+		return nil, err
+	}
+
+	lodReader, lodCloser, err := lam.openReader()
+	if err != nil {
+		return nil, err
+	}
+	defer lodCloser.Close()
+	size, err := lam.archiveSize(lodReader)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, io.NewSectionReader(lodReader, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("can't hash lod archive(%s): %w", lam.ArchiveFilePath, err)
+	}
+
+	manifest := LodManifest{
+		Archive: filepath.Base(lam.ArchiveFilePath),
+		LodType: lam.LodType,
+		Size:    size,
+		SHA256:  hex.EncodeToString(h.Sum(nil)),
+		Files:   make([]LodManifestFile, 0, len(lam.Files)),
+	}
+	for _, file := range lam.Files {
+		manifest.Files = append(manifest.Files, LodManifestFile{
+			Name:         file.Name,
+			OriginalSize: file.OriginalSize,
+			SHA256:       file.SHA256,
+			CRC32:        file.CRC32,
+		})
+	}
+	return &manifest, nil
+}
+
+func LoadLodManifest(pathToManifest string) (*LodManifest, error) {
+	manifestFile, err := os.Open(pathToManifest)
+	if err != nil {
+		return nil, err
+	}
+	defer manifestFile.Close()
+
+	var manifest LodManifest
+	err = json.NewDecoder(manifestFile).Decode(&manifest)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode manifest(%s): %w", pathToManifest, err)
+	}
+	return &manifest, nil
+}
+
+func (lm *LodManifest) WriteJsonFile(pathToManifest string) error {
+	f, err := os.Create(pathToManifest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	jsonEncoder := json.NewEncoder(f)
+	jsonEncoder.SetIndent("", "    ")
+	return jsonEncoder.Encode(lm)
+}
+
+// signedLodManifest mirrors LodManifest field by field, but keeps the lod type a number,
+// because manifests were signed before lod types got JSON names
+type signedLodManifest struct {
+	Archive string            `json:"archive"`
+	LodType uint32            `json:"lod_type"`
+	Size    int64             `json:"size"`
+	SHA256  string            `json:"sha256"`
+	Files   []LodManifestFile `json:"files"`
+}
+
+func (lm *LodManifest) signedPayload() ([]byte, error) {
+	return json.Marshal(signedLodManifest{
+		Archive: lm.Archive,
+		LodType: uint32(lm.LodType),
+		Size:    lm.Size,
+		SHA256:  lm.SHA256,
+		Files:   lm.Files,
+	})
+}
+
+func (lm *LodManifest) Sign(privateKey ed25519.PrivateKey) error {
+	payload, err := lm.signedPayload()
+	if err != nil {
+		return err
+	}
+	lm.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, payload))
+	return nil
+}
+
+// VerifySignature returns ErrManifestNotSigned for manifests without signature
+func (lm *LodManifest) VerifySignature(publicKey ed25519.PublicKey) error {
+	if lm.Signature == "" {
+		return ErrManifestNotSigned
+	}
+	signature, err := base64.StdEncoding.DecodeString(lm.Signature)
+	if err != nil {
+		return fmt.Errorf("can't decode manifest signature: %w", err)
+	}
+	payload, err := lm.signedPayload()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return errors.New("manifest signature is invalid")
+	}
+	return nil
+}
+
+// Check compares files of an installed archive with the manifest, no entries are returned when they match.
+// Files are compared by name ignoring case and by the hash of the original content.
+func (lm *LodManifest) Check(lam *LodArchiveMeta) ([]LodDiffEntry, error) {
+	published := LodArchiveMeta{
+		ArchiveFilePath: lm.Archive,
+		LodType:         lm.LodType,
+		NumberOfFiles:   uint32(len(lm.Files)),
+		Files:           make([]LodFileMeta, 0, len(lm.Files)),
+	}
+	for _, file := range lm.Files {
+		published.Files = append(published.Files, LodFileMeta{
+			Name:         file.Name,
+			OriginalSize: file.OriginalSize,
+			SHA256:       file.SHA256,
+			CRC32:        file.CRC32,
+		})
+	}
+	// manifest files have hashes, so the published archive itself is never read
+	published.reader = noContentReader{}
+	published.hashesOnly = true
+
+	diff, err := DiffLodArchives(&published, lam)
+	if err != nil {
+		return nil, err
+	}
+	return diff.Entries, nil
+}
+
+type noContentReader struct{}
+
+func (noContentReader) ReadAt([]byte, int64) (int, error) {
+	return 0, errors.New("manifest has no content")
+}
@@ -0,0 +1,174 @@
+package lodparse
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+type VerifyProblemKind string
+
+const (
+	ProblemHeader         VerifyProblemKind = "header"
+	ProblemFilesCount     VerifyProblemKind = "files_count"
+	ProblemOutOfBounds    VerifyProblemKind = "out_of_bounds"
+	ProblemOverlap        VerifyProblemKind = "overlap"
+	ProblemDuplicatedName VerifyProblemKind = "duplicated_name"
+	ProblemCaseCollision  VerifyProblemKind = "case_collision"
+	ProblemNotTerminated  VerifyProblemKind = "name_not_terminated"
+	ProblemEmptyName      VerifyProblemKind = "empty_name"
+	ProblemInflate        VerifyProblemKind = "inflate"
+	ProblemOriginalSize   VerifyProblemKind = "original_size"
+)
+
+// VerifyProblem is a single problem found by Verify, File is empty for problems of the whole archive
+type VerifyProblem struct {
+	Kind    VerifyProblemKind `json:"kind"`
+	File    string            `json:"file,omitempty"`
+	Message string            `json:"message"`
+}
+
+func (vp VerifyProblem) String() string {
+	if vp.File == "" {
+		return fmt.Sprintf("%s: %s", vp.Kind, vp.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", vp.Kind, vp.File, vp.Message)
+}
+
+// Verify checks the archive files table and content against the meta and returns all found problems.
+// Error is returned only when the archive can't be read at all or it has unsaved changes.
+func (lam *LodArchiveMeta) Verify() ([]VerifyProblem, error) {
+	if lam.HasChanges() {
+		return nil, ErrUnsavedChanges
+	}
+	lodReader, lodCloser, err := lam.openReader()
+	if err != nil {
+		return nil, err
+	}
+	defer lodCloser.Close()
+	size, err := lam.archiveSize(lodReader)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []VerifyProblem
+	report := func(kind VerifyProblemKind, file string, format string, args ...interface{}) {
+		problems = append(problems, VerifyProblem{Kind: kind, File: file, Message: fmt.Sprintf(format, args...)})
+	}
+
+	header := make([]byte, lodHeaderSize)
+	_, err = lodReader.ReadAt(header, 0)
+	if err != nil {
+		report(ProblemHeader, "", "can't read header: %v", err)
+		return problems, nil
+	}
+	if binary.LittleEndian.Uint32(header) != lodArchiveHeader {
+		report(ProblemHeader, "", "wrong header value")
+	}
+	tableFilesCount := binary.LittleEndian.Uint32(header[8:])
+	if tableFilesCount != lam.NumberOfFiles {
+		report(ProblemFilesCount, "", "header tells %d files, meta tells %d", tableFilesCount, lam.NumberOfFiles)
+	}
+	if int(lam.NumberOfFiles) != len(lam.Files) {
+		report(ProblemFilesCount, "", "meta tells %d files, but has %d", lam.NumberOfFiles, len(lam.Files))
+	}
+
+	tableEnd := int64(lodHeaderSize) + int64(lodFileEntrySize)*int64(tableFilesCount)
+	if tableEnd > size {
+		report(ProblemFilesCount, "", "files table of %d files doesn't fit into archive of %d bytes", tableFilesCount, size)
+		tableEnd = size
+	}
+	problems = append(problems, verifyTableNames(lodReader, tableEnd)...)
+
+	names := make(map[string]bool, len(lam.Files))
+	for _, group := range lam.CaseCollisions() {
+		report(ProblemCaseCollision, group[0].Name, "%d files have the same name ignoring case", len(group))
+	}
+	for _, file := range lam.Files {
+		if names[file.Name] {
+			report(ProblemDuplicatedName, file.Name, "name is used by more than one file")
+		}
+		names[file.Name] = true
+
+		end := int64(file.Offset) + int64(file.storedSize())
+		if int64(file.Offset) < tableEnd || end > size {
+			report(ProblemOutOfBounds, file.Name, "content [%d, %d) is outside of archive content [%d, %d)", file.Offset, end, tableEnd, size)
+			continue
+		}
+		if !file.IsCompressed() {
+			continue
+		}
+		inflated, err := inflatedSize(lodReader, file)
+		if err != nil {
+			report(ProblemInflate, file.Name, "can't inflate: %v", err)
+		} else if inflated != int64(file.OriginalSize) {
+			report(ProblemOriginalSize, file.Name, "inflates to %d bytes, original size is %d", inflated, file.OriginalSize)
+		}
+	}
+
+	problems = append(problems, verifyOverlaps(lam.Files)...)
+	return problems, nil
+}
+
+// verifyTableNames checks raw names in the files table, because parsed names lose the terminating null
+func verifyTableNames(lodReader io.ReaderAt, tableEnd int64) []VerifyProblem {
+	var problems []VerifyProblem
+	table := io.NewSectionReader(lodReader, lodHeaderSize, tableEnd-lodHeaderSize)
+	entry := make([]byte, lodFileEntrySize)
+	for i := 0; ; i++ {
+		_, err := io.ReadFull(table, entry)
+		if err != nil {
+			break
+		}
+		name := entry[:lodFileNameSize]
+		nameEnd := bytes.IndexByte(name, 0)
+		switch {
+		case nameEnd == -1:
+			problems = append(problems, VerifyProblem{
+				Kind: ProblemNotTerminated, File: string(name),
+				Message: fmt.Sprintf("name of file [%d] is not null-terminated", i),
+			})
+		case nameEnd == 0:
+			problems = append(problems, VerifyProblem{
+				Kind: ProblemEmptyName, Message: fmt.Sprintf("name of file [%d] is empty", i),
+			})
+		}
+	}
+	return problems
+}
+
+func verifyOverlaps(files []LodFileMeta) []VerifyProblem {
+	sorted := make([]LodFileMeta, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	var problems []VerifyProblem
+	for i := 1; i < len(sorted); i++ {
+		prev, file := sorted[i-1], sorted[i]
+		prevEnd := int64(prev.Offset) + int64(prev.storedSize())
+		if prevEnd > int64(file.Offset) {
+			problems = append(problems, VerifyProblem{
+				Kind:    ProblemOverlap,
+				File:    file.Name,
+				Message: fmt.Sprintf("content overlaps with %s by %d bytes", prev.Name, prevEnd-int64(file.Offset)),
+			})
+		}
+	}
+	return problems
+}
+
+// inflatedSize inflates the stored content without checking its size against the original one
+func inflatedSize(lodReader io.ReaderAt, file LodFileMeta) (int64, error) {
+	zr, err := zlib.NewReader(io.NewSectionReader(lodReader, int64(file.Offset), int64(file.storedSize())))
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+	return io.Copy(ioutil.Discard, zr)
+}
@@ -1,12 +1,27 @@
 package lodparse_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/netscrn/homm3utils/lodparse"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 )
 
 var tempDirPath string
@@ -136,3 +151,1121 @@ func TestExtractLodFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestPackLodDir(t *testing.T) {
+	srcDir := filepath.Join(".", "testdata", "HotA_lng_files")
+	packedLodPath := filepath.Join(tempDirPath, "packed.lod")
+	_, err := lodparse.PackLodDir(srcDir, packedLodPath, lodparse.Expansion, lodparse.CompressAuto)
+	if err != nil {
+		t.Fatalf("Can't pack lod archive: %v", err)
+	}
+
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(packedLodPath)
+	if err != nil {
+		t.Fatalf("Can't load packed lod archive meta: %v", err)
+	}
+	if !lam.LodType.IsExpansionType() {
+		t.Error("Wrong lod type")
+	}
+	if lam.NumberOfFiles != 209 {
+		t.Errorf("Wrong number of files in packed lod archive: %d", lam.NumberOfFiles)
+	}
+	if lam.Files[0].Name != "advevent.txt" {
+		t.Errorf("Files are not ordered case-insensitively, first file is %s", lam.Files[0].Name)
+	}
+
+	unpackedDir := filepath.Join(tempDirPath, "unpacked")
+	err = os.Mkdir(unpackedDir, 0700)
+	if err != nil {
+		t.Fatalf("Can't create dir for unpacked files: %v", err)
+	}
+	err = lodparse.ExtractLodFiles(lam, unpackedDir, 0)
+	if err != nil {
+		t.Fatalf("Can't extract packed lod archive: %v", err)
+	}
+
+	for _, file := range lam.Files {
+		original, err := os.ReadFile(filepath.Join(srcDir, file.Name))
+		if err != nil {
+			t.Fatalf("Can't read original file: %v", err)
+		}
+		unpacked, err := os.ReadFile(filepath.Join(unpackedDir, file.Name))
+		if err != nil {
+			t.Fatalf("Can't read unpacked file: %v", err)
+		}
+		if !reflect.DeepEqual(original, unpacked) {
+			t.Errorf("Unpacked file(%s) differs from original", file.Name)
+		}
+	}
+}
+
+// loadPackedTestLod packs trusted extracted files into a lod archive once and loads its meta
+func loadPackedTestLod(t *testing.T) *lodparse.LodArchiveMeta {
+	t.Helper()
+	packedLodPath := filepath.Join(tempDirPath, "fixture.lod")
+	if _, err := os.Stat(packedLodPath); err != nil {
+		_, err = lodparse.PackLodDir(filepath.Join(".", "testdata", "HotA_lng_files"), packedLodPath, lodparse.LodArchiveType(0x1F4), lodparse.CompressAuto)
+		if err != nil {
+			t.Fatalf("Can't pack test lod archive: %v", err)
+		}
+	}
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(packedLodPath)
+	if err != nil {
+		t.Fatalf("Can't load packed test lod archive meta: %v", err)
+	}
+	return lam
+}
+
+func TestLodArchiveMetaFS(t *testing.T) {
+	lam := loadPackedTestLod(t)
+	lodFS := lam.FS()
+
+	names := make([]string, 0, len(lam.Files))
+	for _, file := range lam.Files {
+		names = append(names, file.Name)
+	}
+	err := fstest.TestFS(lodFS, names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := fs.ReadFile(lodFS, "AVArnd1.def")
+	if err != nil {
+		t.Fatalf("Can't read file from lod fs: %v", err)
+	}
+	original, err := os.ReadFile(filepath.Join(".", "testdata", "HotA_lng_files", "AVArnd1.def"))
+	if err != nil {
+		t.Fatalf("Can't read original file: %v", err)
+	}
+	if !reflect.DeepEqual(original, content) {
+		t.Error("File read from lod fs differs from original")
+	}
+
+	info, err := fs.Stat(lodFS, "AVArnd1.def")
+	if err != nil {
+		t.Fatalf("Can't stat file in lod fs: %v", err)
+	}
+	if info.Size() != int64(len(original)) {
+		t.Errorf("Wrong size of file in lod fs: %d", info.Size())
+	}
+
+	matches, err := fs.Glob(lodFS, "*.msk")
+	if err != nil {
+		t.Fatalf("Can't glob lod fs: %v", err)
+	}
+	if len(matches) == 0 {
+		t.Error("No .msk files found in lod fs")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	lam := loadPackedTestLod(t)
+
+	for _, name := range []string{"AVArnd1.def", "AVArnd1.msk"} {
+		original, err := os.ReadFile(filepath.Join(".", "testdata", "HotA_lng_files", name))
+		if err != nil {
+			t.Fatalf("Can't read original file: %v", err)
+		}
+
+		content, err := lam.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Can't read file(%s) from lod archive: %v", name, err)
+		}
+		if !reflect.DeepEqual(original, content) {
+			t.Errorf("File(%s) read from lod archive differs from original", name)
+		}
+
+		r, err := lam.Open(name)
+		if err != nil {
+			t.Fatalf("Can't open file(%s) in lod archive: %v", name, err)
+		}
+		content, err = io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("Can't read opened file(%s): %v", name, err)
+		}
+		if !reflect.DeepEqual(original, content) {
+			t.Errorf("Opened file(%s) differs from original", name)
+		}
+	}
+
+	_, err := lam.ReadFile("nonexistent.def")
+	if err == nil {
+		t.Error("Expected error reading nonexistent file")
+	}
+}
+
+func TestParseLod(t *testing.T) {
+	packed := loadPackedTestLod(t)
+	lodBytes, err := os.ReadFile(packed.ArchiveFilePath)
+	if err != nil {
+		t.Fatalf("Can't read packed lod archive: %v", err)
+	}
+
+	lam, err := lodparse.ParseLod(bytes.NewReader(lodBytes), int64(len(lodBytes)))
+	if err != nil {
+		t.Fatalf("Can't parse lod archive from memory: %v", err)
+	}
+	if lam.NumberOfFiles != packed.NumberOfFiles {
+		t.Errorf("Wrong number of files: %d", lam.NumberOfFiles)
+	}
+
+	dstDir := filepath.Join(tempDirPath, "from_memory")
+	err = os.Mkdir(dstDir, 0700)
+	if err != nil {
+		t.Fatalf("Can't create dir for extracted files: %v", err)
+	}
+	err = lodparse.ExtractLodFiles(lam, dstDir, 0)
+	if err != nil {
+		t.Fatalf("Can't extract lod archive parsed from memory: %v", err)
+	}
+	original, err := os.ReadFile(filepath.Join(".", "testdata", "HotA_lng_files", "advevent.txt"))
+	if err != nil {
+		t.Fatalf("Can't read original file: %v", err)
+	}
+	extracted, err := os.ReadFile(filepath.Join(dstDir, "advevent.txt"))
+	if err != nil {
+		t.Fatalf("Can't read extracted file: %v", err)
+	}
+	if !reflect.DeepEqual(original, extracted) {
+		t.Error("Extracted file differs from original")
+	}
+
+	_, err = lodparse.ParseLod(bytes.NewReader(lodBytes[:1000]), 1000)
+	if err == nil {
+		t.Error("Expected error parsing truncated lod archive")
+	}
+}
+
+func TestExtractLodFilesContext(t *testing.T) {
+	packed := loadPackedTestLod(t)
+	lodBytes, err := os.ReadFile(packed.ArchiveFilePath)
+	if err != nil {
+		t.Fatalf("Can't read packed lod archive: %v", err)
+	}
+	brokenFile, err := packed.GetFile("advevent.txt")
+	if err != nil {
+		t.Fatalf("Can't get file meta: %v", err)
+	}
+	copy(lodBytes[brokenFile.Offset:], []byte{0, 0, 0, 0})
+	lam, err := lodparse.ParseLod(bytes.NewReader(lodBytes), int64(len(lodBytes)))
+	if err != nil {
+		t.Fatalf("Can't parse lod archive from memory: %v", err)
+	}
+
+	dstDir := filepath.Join(tempDirPath, "continue_on_error")
+	err = os.Mkdir(dstDir, 0700)
+	if err != nil {
+		t.Fatalf("Can't create dir for extracted files: %v", err)
+	}
+	var last lodparse.ExtractProgress
+	err = lodparse.ExtractLodFilesContext(context.Background(), lam, dstDir, lodparse.ExtractOptions{
+		ContinueOnError: true,
+		Progress: func(p lodparse.ExtractProgress) {
+			last = p
+		},
+	})
+	var extractErrs lodparse.ExtractErrors
+	if !errors.As(err, &extractErrs) {
+		t.Fatalf("Expected ExtractErrors, got: %v", err)
+	}
+	if len(extractErrs) != 1 || extractErrs[0].File.Name != brokenFile.Name {
+		t.Errorf("Wrong extract errors: %v", extractErrs)
+	}
+	if last.FilesDone != int(lam.NumberOfFiles)-1 || last.FilesTotal != int(lam.NumberOfFiles) {
+		t.Errorf("Wrong progress: %d/%d", last.FilesDone, last.FilesTotal)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = lodparse.ExtractLodFilesContext(ctx, packed, dstDir, lodparse.ExtractOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func bytesSource(name string, content []byte) lodparse.LodFileSource {
+	return lodparse.LodFileSource{
+		Name: name,
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		},
+	}
+}
+
+func TestCaseInsensitiveLookups(t *testing.T) {
+	lam := loadPackedTestLod(t)
+
+	file, err := lam.GetFile("AVARND1.DEF")
+	if err != nil {
+		t.Fatalf("Can't get file ignoring case: %v", err)
+	}
+	if file.Name != "AVArnd1.def" {
+		t.Errorf("Wrong file found: %s", file.Name)
+	}
+
+	defs, err := lam.Glob("AVA*.DEF")
+	if err != nil {
+		t.Fatalf("Can't glob files: %v", err)
+	}
+	if len(defs) != 5 {
+		t.Errorf("Wrong number of globbed files: %d", len(defs))
+	}
+	_, err = lam.Glob("[")
+	if err == nil {
+		t.Error("Expected error for bad glob pattern")
+	}
+
+	small := lam.Filter(func(file lodparse.LodFileMeta) bool {
+		return file.OriginalSize < 100
+	})
+	for _, file := range small {
+		if file.OriginalSize >= 100 {
+			t.Errorf("Filtered file(%s) is too big", file.Name)
+		}
+	}
+
+	if len(lam.CaseCollisions()) != 0 {
+		t.Error("Unexpected case collisions in test archive")
+	}
+
+	collidingLodPath := filepath.Join(tempDirPath, "colliding.lod")
+	colliding, err := lodparse.CreateLodArchive(collidingLodPath, lodparse.Base, []lodparse.LodFileSource{
+		bytesSource("Sprite.def", []byte("first")),
+		bytesSource("SPRITE.DEF", []byte("second")),
+		bytesSource("other.txt", []byte("other")),
+	})
+	if err != nil {
+		t.Fatalf("Can't create colliding lod archive: %v", err)
+	}
+	collisions := colliding.CaseCollisions()
+	if len(collisions) != 1 || len(collisions[0]) != 2 {
+		t.Fatalf("Wrong case collisions: %v", collisions)
+	}
+	content, err := colliding.ReadFile("SPRITE.DEF")
+	if err != nil || string(content) != "second" {
+		t.Errorf("Exactly matching name should win: %q, %v", content, err)
+	}
+	content, err = colliding.ReadFile("sprite.def")
+	if err != nil || string(content) != "first" {
+		t.Errorf("First colliding file should win: %q, %v", content, err)
+	}
+}
+
+func TestResourceResolver(t *testing.T) {
+	base := loadPackedTestLod(t)
+	override, err := lodparse.CreateLodArchive(filepath.Join(tempDirPath, "override.lod"), lodparse.Expansion, []lodparse.LodFileSource{
+		bytesSource("ADVEVENT.TXT", []byte("overridden")),
+		bytesSource("new.txt", []byte("new")),
+	})
+	if err != nil {
+		t.Fatalf("Can't create override lod archive: %v", err)
+	}
+	looseDir := filepath.Join(tempDirPath, "loose")
+	err = os.Mkdir(looseDir, 0700)
+	if err != nil {
+		t.Fatalf("Can't create loose resources dir: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(looseDir, "New.txt"), []byte("loose"), 0600)
+	if err != nil {
+		t.Fatalf("Can't write loose resource: %v", err)
+	}
+
+	rr := lodparse.NewResourceResolver()
+	rr.AddArchive(base)
+	rr.AddArchive(override)
+	err = rr.AddDir(looseDir)
+	if err != nil {
+		t.Fatalf("Can't add loose resources dir: %v", err)
+	}
+
+	resource, err := rr.Resolve("advevent.txt")
+	if err != nil {
+		t.Fatalf("Can't resolve resource: %v", err)
+	}
+	if resource.Layer.Archive != override {
+		t.Errorf("Resource resolved to wrong layer: %s", resource.Layer)
+	}
+	content, err := rr.ReadFile("advevent.txt")
+	if err != nil || string(content) != "overridden" {
+		t.Errorf("Wrong winning content: %q, %v", content, err)
+	}
+	content, err = rr.ReadFile("NEW.TXT")
+	if err != nil || string(content) != "loose" {
+		t.Errorf("Wrong winning content of loose resource: %q, %v", content, err)
+	}
+	content, err = rr.ReadFile("AVArnd1.msk")
+	if err != nil || len(content) == 0 {
+		t.Errorf("Can't read resource from base layer: %v", err)
+	}
+
+	shadowed := rr.Shadowed()
+	if len(shadowed) != 2 {
+		t.Fatalf("Wrong number of shadowed resources: %d", len(shadowed))
+	}
+	if shadowed[0].Winner.Name != "ADVEVENT.TXT" || shadowed[0].Shadowed[0].Layer.Archive != base {
+		t.Errorf("Wrong shadowed resource: %+v", shadowed[0])
+	}
+	if len(rr.Resources()) != int(base.NumberOfFiles)+1 {
+		t.Errorf("Wrong number of resources: %d", len(rr.Resources()))
+	}
+
+	_, err = rr.Resolve("missing.def")
+	if err == nil {
+		t.Error("Expected error resolving missing resource")
+	}
+}
+
+func TestEditAndSave(t *testing.T) {
+	packed := loadPackedTestLod(t)
+	editedLodPath := filepath.Join(tempDirPath, "edited.lod")
+	err := packed.Save(editedLodPath)
+	if err != nil {
+		t.Fatalf("Can't save lod archive copy: %v", err)
+	}
+	packedBytes, err := os.ReadFile(filepath.Join(tempDirPath, "fixture.lod"))
+	if err != nil {
+		t.Fatalf("Can't read packed lod archive: %v", err)
+	}
+	savedBytes, err := os.ReadFile(editedLodPath)
+	if err != nil {
+		t.Fatalf("Can't read saved lod archive: %v", err)
+	}
+	if !bytes.Equal(packedBytes, savedBytes) {
+		t.Error("Saved archive without changes differs from original")
+	}
+
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(editedLodPath)
+	if err != nil {
+		t.Fatalf("Can't load lod archive meta: %v", err)
+	}
+	untouched, err := lam.GetFile("HeroBios.txt")
+	if err != nil {
+		t.Fatalf("Can't get file meta: %v", err)
+	}
+	untouchedStored := savedBytes[untouched.Offset : untouched.Offset+untouched.CompressedSize]
+
+	if err = lam.Add(bytesSource("added.txt", []byte("added"))); err != nil {
+		t.Fatalf("Can't add file: %v", err)
+	}
+	if err = lam.Add(bytesSource("ADDED.TXT", []byte("added"))); err == nil {
+		t.Error("Expected error adding existing file")
+	}
+	if err = lam.Replace(bytesSource("advevent.txt", []byte("replaced"))); err != nil {
+		t.Fatalf("Can't replace file: %v", err)
+	}
+	if err = lam.Remove("AVArnd1.def"); err != nil {
+		t.Fatalf("Can't remove file: %v", err)
+	}
+	if err = lam.Rename("AVArnd1.msk", "renamed.msk"); err != nil {
+		t.Fatalf("Can't rename file: %v", err)
+	}
+	if !lam.HasChanges() {
+		t.Error("Archive should have changes")
+	}
+	content, err := lam.ReadFile("added.txt")
+	if err != nil || string(content) != "added" {
+		t.Errorf("Wrong content of unsaved added file: %q, %v", content, err)
+	}
+
+	err = lam.Save(editedLodPath)
+	if err != nil {
+		t.Fatalf("Can't save edited lod archive: %v", err)
+	}
+
+	saved, err := lodparse.LoadLodArchiveMetaFromLodFile(editedLodPath)
+	if err != nil {
+		t.Fatalf("Can't load saved lod archive meta: %v", err)
+	}
+	if saved.NumberOfFiles != packed.NumberOfFiles {
+		t.Errorf("Wrong number of files after edit: %d", saved.NumberOfFiles)
+	}
+	if saved.Files[0].Name != "added.txt" {
+		t.Errorf("Added file should keep name order, first file is %s", saved.Files[0].Name)
+	}
+	expected := map[string]string{"added.txt": "added", "advevent.txt": "replaced"}
+	for name, want := range expected {
+		content, err := saved.ReadFile(name)
+		if err != nil || string(content) != want {
+			t.Errorf("Wrong content of %s: %q, %v", name, content, err)
+		}
+	}
+	if _, err = saved.GetFile("AVArnd1.def"); err == nil {
+		t.Error("Removed file is still in archive")
+	}
+	if _, err = saved.GetFile("renamed.msk"); err != nil {
+		t.Error("Renamed file is not in archive")
+	}
+
+	savedBytes, err = os.ReadFile(editedLodPath)
+	if err != nil {
+		t.Fatalf("Can't read saved lod archive: %v", err)
+	}
+	untouched, err = saved.GetFile("HeroBios.txt")
+	if err != nil {
+		t.Fatalf("Can't get file meta: %v", err)
+	}
+	if !bytes.Equal(untouchedStored, savedBytes[untouched.Offset:untouched.Offset+untouched.CompressedSize]) {
+		t.Error("Stored bytes of untouched file have changed")
+	}
+}
+
+func TestUnsavedChanges(t *testing.T) {
+	original := loadPackedTestLod(t)
+	lam := loadPackedTestLod(t)
+	added, replaced := []byte("added file"), []byte("replaced content")
+	if err := lam.Add(bytesSource("c.txt", added)); err != nil {
+		t.Fatalf("Can't add file: %v", err)
+	}
+	if err := lam.Replace(bytesSource("advevent.txt", replaced)); err != nil {
+		t.Fatalf("Can't replace file: %v", err)
+	}
+	expected := map[string][]byte{"c.txt": added, "advevent.txt": replaced}
+
+	dstDir := filepath.Join(tempDirPath, "unsaved")
+	err := os.Mkdir(dstDir, 0700)
+	if err != nil {
+		t.Fatalf("Can't create dst dir: %v", err)
+	}
+	err = lodparse.ExtractLodFiles(lam, dstDir, 2)
+	if err != nil {
+		t.Fatalf("Can't extract edited archive: %v", err)
+	}
+	for name, content := range expected {
+		extracted, err := os.ReadFile(filepath.Join(dstDir, name))
+		if err != nil || !bytes.Equal(extracted, content) {
+			t.Errorf("Wrong extracted content of %s: %q, %v", name, extracted, err)
+		}
+
+		info, err := fs.Stat(lam.FS(), name)
+		if err != nil || info.Size() != int64(len(content)) {
+			t.Errorf("Wrong stat of %s: %v, %v", name, info, err)
+		}
+	}
+
+	err = lam.ComputeHashes()
+	if err != nil {
+		t.Fatalf("Can't hash edited archive: %v", err)
+	}
+	for name, content := range expected {
+		file, _ := lam.GetFile(name)
+		sha := sha256.Sum256(content)
+		if file.SHA256 != hex.EncodeToString(sha[:]) {
+			t.Errorf("Wrong hash of %s: %s", name, file.SHA256)
+		}
+	}
+
+	diff, err := lodparse.DiffLodArchives(original, lam)
+	if err != nil {
+		t.Fatalf("Can't diff edited archive: %v", err)
+	}
+	kinds := make(map[string]lodparse.LodDiffKind)
+	for _, entry := range diff.Entries {
+		kinds[entry.Name] = entry.Kind
+	}
+	if len(kinds) != 2 || kinds["c.txt"] != lodparse.DiffAdded || kinds["advevent.txt"] == "" {
+		t.Errorf("Wrong diff of edited archive: %v", diff.Entries)
+	}
+
+	if _, err = lam.Verify(); !errors.Is(err, lodparse.ErrUnsavedChanges) {
+		t.Errorf("Verify of edited archive should fail with unsaved changes, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	packed := loadPackedTestLod(t)
+	problems, err := packed.Verify()
+	if err != nil {
+		t.Fatalf("Can't verify lod archive: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("Unexpected problems in packed archive: %v", problems)
+	}
+
+	lodBytes, err := os.ReadFile(packed.ArchiveFilePath)
+	if err != nil {
+		t.Fatalf("Can't read packed lod archive: %v", err)
+	}
+	entry := func(i int) []byte {
+		return lodBytes[92+32*i : 92+32*(i+1)]
+	}
+	copy(lodBytes[packed.Files[0].Offset:], []byte{0, 0, 0, 0})
+	copy(entry(1)[:16], bytes.Repeat([]byte{'A'}, 16))
+	binary.LittleEndian.PutUint32(entry(3)[16:], packed.Files[2].Offset)
+	lodBytes = lodBytes[:len(lodBytes)-10]
+
+	lam, err := lodparse.ParseLod(bytes.NewReader(lodBytes), int64(len(lodBytes)))
+	if err != nil {
+		t.Fatalf("Can't parse corrupted lod archive: %v", err)
+	}
+	problems, err = lam.Verify()
+	if err != nil {
+		t.Fatalf("Can't verify corrupted lod archive: %v", err)
+	}
+	found := make(map[lodparse.VerifyProblemKind]bool)
+	for _, problem := range problems {
+		found[problem.Kind] = true
+	}
+	expected := []lodparse.VerifyProblemKind{
+		lodparse.ProblemInflate,
+		lodparse.ProblemNotTerminated,
+		lodparse.ProblemOverlap,
+		lodparse.ProblemOutOfBounds,
+	}
+	for _, kind := range expected {
+		if !found[kind] {
+			t.Errorf("Problem %s is not found in %v", kind, problems)
+		}
+	}
+}
+
+func TestDiffLodArchives(t *testing.T) {
+	oldArchive, err := lodparse.CreateLodArchive(filepath.Join(tempDirPath, "diff_old.lod"), lodparse.Base, []lodparse.LodFileSource{
+		bytesSource("same.txt", []byte("same")),
+		bytesSource("removed.txt", []byte("removed")),
+		bytesSource("resized.txt", []byte("short")),
+		bytesSource("changed.txt", []byte("before")),
+	})
+	if err != nil {
+		t.Fatalf("Can't create old lod archive: %v", err)
+	}
+	newArchive, err := lodparse.CreateLodArchive(filepath.Join(tempDirPath, "diff_new.lod"), lodparse.Base, []lodparse.LodFileSource{
+		bytesSource("SAME.TXT", []byte("same")),
+		bytesSource("resized.txt", []byte("much longer")),
+		bytesSource("changed.txt", []byte("after!")),
+		bytesSource("added.txt", []byte("added")),
+	})
+	if err != nil {
+		t.Fatalf("Can't create new lod archive: %v", err)
+	}
+
+	diff, err := lodparse.DiffLodArchives(oldArchive, newArchive)
+	if err != nil {
+		t.Fatalf("Can't diff lod archives: %v", err)
+	}
+	if diff.Unchanged != 1 {
+		t.Errorf("Wrong number of unchanged files: %d", diff.Unchanged)
+	}
+	expected := []lodparse.LodDiffEntry{
+		{Kind: lodparse.DiffAdded, Name: "added.txt"},
+		{Kind: lodparse.DiffChanged, Name: "changed.txt"},
+		{Kind: lodparse.DiffRemoved, Name: "removed.txt"},
+		{Kind: lodparse.DiffResized, Name: "resized.txt"},
+	}
+	if len(diff.Entries) != len(expected) {
+		t.Fatalf("Wrong diff entries: %v", diff.Entries)
+	}
+	for i, entry := range diff.Entries {
+		if entry.Kind != expected[i].Kind || entry.Name != expected[i].Name {
+			t.Errorf("Wrong diff entry: %v, expected %s %s", entry, expected[i].Kind, expected[i].Name)
+		}
+	}
+	if diff.Entries[1].OldHash == "" || diff.Entries[1].OldHash == diff.Entries[1].NewHash {
+		t.Errorf("Wrong hashes of changed file: %v", diff.Entries[1])
+	}
+
+	// stored hashes may be left from an older content, like ones of meta loaded from json
+	for _, archive := range []*lodparse.LodArchiveMeta{oldArchive, newArchive} {
+		for i := range archive.Files {
+			archive.Files[i].SHA256 = "stale"
+		}
+	}
+	diff, err = lodparse.DiffLodArchives(oldArchive, newArchive)
+	if err != nil {
+		t.Fatalf("Can't diff lod archives with stale hashes: %v", err)
+	}
+	if len(diff.Entries) != len(expected) || diff.Entries[1].Name != "changed.txt" || diff.Entries[1].NewHash == "stale" {
+		t.Errorf("Stale hashes are used in diff: %v", diff.Entries)
+	}
+}
+
+func TestLodManifest(t *testing.T) {
+	published, err := lodparse.CreateLodArchive(filepath.Join(tempDirPath, "published.lod"), lodparse.Base, []lodparse.LodFileSource{
+		bytesSource("first.txt", []byte("first")),
+		bytesSource("second.txt", []byte("second")),
+	})
+	if err != nil {
+		t.Fatalf("Can't create published lod archive: %v", err)
+	}
+	manifest, err := lodparse.NewLodManifest(published)
+	if err != nil {
+		t.Fatalf("Can't create manifest: %v", err)
+	}
+	if published.Files[0].SHA256 == "" || published.Files[0].CRC32 == "" {
+		t.Error("File hashes are not computed")
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Can't generate key: %v", err)
+	}
+	if err = manifest.VerifySignature(publicKey); !errors.Is(err, lodparse.ErrManifestNotSigned) {
+		t.Errorf("Expected ErrManifestNotSigned, got %v", err)
+	}
+	if err = manifest.Sign(privateKey); err != nil {
+		t.Fatalf("Can't sign manifest: %v", err)
+	}
+	manifestPath := filepath.Join(tempDirPath, "manifest.json")
+	if err = manifest.WriteJsonFile(manifestPath); err != nil {
+		t.Fatalf("Can't write manifest: %v", err)
+	}
+	loaded, err := lodparse.LoadLodManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("Can't load manifest: %v", err)
+	}
+	if err = loaded.VerifySignature(publicKey); err != nil {
+		t.Errorf("Valid signature is rejected: %v", err)
+	}
+	loaded.Files[0].SHA256 = loaded.Files[1].SHA256
+	if err = loaded.VerifySignature(publicKey); err == nil {
+		t.Error("Signature of tampered manifest is accepted")
+	}
+
+	mismatches, err := manifest.Check(published)
+	if err != nil || len(mismatches) != 0 {
+		t.Errorf("Published archive doesn't match its manifest: %v, %v", mismatches, err)
+	}
+	installed, err := lodparse.CreateLodArchive(filepath.Join(tempDirPath, "installed.lod"), lodparse.Base, []lodparse.LodFileSource{
+		bytesSource("FIRST.TXT", []byte("first")),
+		bytesSource("second.txt", []byte("Second")),
+	})
+	if err != nil {
+		t.Fatalf("Can't create installed lod archive: %v", err)
+	}
+	mismatches, err = manifest.Check(installed)
+	if err != nil {
+		t.Fatalf("Can't check installed archive: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Name != "second.txt" || mismatches[0].Kind != lodparse.DiffChanged {
+		t.Errorf("Wrong mismatches: %v", mismatches)
+	}
+}
+
+func TestSelectiveExtraction(t *testing.T) {
+	lam := loadPackedTestLod(t)
+	filter := lodparse.LodFileFilter{
+		Include:    []string{"AV*"},
+		Exclude:    []string{"avw*"},
+		Extensions: []string{"DEF"},
+		MaxSize:    5000,
+	}
+	if err := filter.Validate(); err != nil {
+		t.Fatalf("Valid filter is rejected: %v", err)
+	}
+	if err := (lodparse.LodFileFilter{Include: []string{"["}}).Validate(); err == nil {
+		t.Error("Filter with bad pattern is accepted")
+	}
+
+	dstDir := filepath.Join(tempDirPath, "selective")
+	err := os.Mkdir(dstDir, 0700)
+	if err != nil {
+		t.Fatalf("Can't create dir for extracted files: %v", err)
+	}
+	err = lodparse.ExtractLodFilesContext(context.Background(), lam, dstDir, lodparse.ExtractOptions{Filter: filter.Match})
+	if err != nil {
+		t.Fatalf("Can't extract filtered files: %v", err)
+	}
+
+	extracted, err := os.ReadDir(dstDir)
+	if err != nil {
+		t.Fatalf("Can't read dir with extracted files: %v", err)
+	}
+	expected := lam.Filter(filter.Match)
+	if len(extracted) == 0 || len(extracted) != len(expected) {
+		t.Fatalf("Wrong number of extracted files: %d, expected %d", len(extracted), len(expected))
+	}
+	for _, file := range expected {
+		if filepath.Ext(file.Name) != ".def" || file.OriginalSize > 5000 || file.Name[:3] == "AVW" {
+			t.Errorf("File(%s) shouldn't match the filter", file.Name)
+		}
+	}
+
+	named := lam.Filter(lodparse.LodFileFilter{Names: []string{"advevent.TXT", "missing.txt"}}.Match)
+	if len(named) != 1 || named[0].Name != "advevent.txt" {
+		t.Errorf("Wrong files selected by names: %v", named)
+	}
+}
+
+func TestExtractConverters(t *testing.T) {
+	lam := loadPackedTestLod(t)
+	var converted []string
+	var mu sync.Mutex
+	upper := func(file lodparse.LodFileMeta, content io.Reader, dstDir string) error {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return err
+		}
+		if len(data) != int(file.OriginalSize) {
+			return fmt.Errorf("converter got %d bytes of %d", len(data), file.OriginalSize)
+		}
+		mu.Lock()
+		converted = append(converted, file.Name)
+		mu.Unlock()
+		return os.WriteFile(filepath.Join(dstDir, file.Name+".conv"), bytes.ToUpper(data), 0644)
+	}
+
+	for _, keepRaw := range []bool{false, true} {
+		converted = nil
+		dstDir, err := os.MkdirTemp(tempDirPath, "converted")
+		if err != nil {
+			t.Fatalf("Can't create dir for converted files: %v", err)
+		}
+		opts := lodparse.ExtractOptions{
+			Filter:     lodparse.LodFileFilter{Include: []string{"a*"}}.Match,
+			Converters: map[string]lodparse.LodFileConverter{".TXT": upper},
+			KeepRaw:    keepRaw,
+		}
+		err = lodparse.ExtractLodFilesContext(context.Background(), lam, dstDir, opts)
+		if err != nil {
+			t.Fatalf("Can't extract with converters: %v", err)
+		}
+
+		for _, file := range lam.Filter(opts.Filter) {
+			_, rawErr := os.Stat(filepath.Join(dstDir, file.Name))
+			_, convErr := os.Stat(filepath.Join(dstDir, file.Name+".conv"))
+			isTxt := strings.EqualFold(filepath.Ext(file.Name), ".txt")
+			if isTxt != (convErr == nil) {
+				t.Errorf("File(%s) converted: %v, expected %v", file.Name, convErr == nil, isTxt)
+			}
+			if (!isTxt || keepRaw) != (rawErr == nil) {
+				t.Errorf("File(%s) raw written: %v, keep raw: %v", file.Name, rawErr == nil, keepRaw)
+			}
+		}
+		if len(converted) == 0 {
+			t.Error("No files converted")
+		}
+	}
+}
+
+func TestByteExactRoundTrip(t *testing.T) {
+	small, err := lodparse.CreateLodArchive(filepath.Join(tempDirPath, "small.lod"), lodparse.Base, []lodparse.LodFileSource{
+		bytesSource("first.txt", bytes.Repeat([]byte("first"), 100)),
+		bytesSource("second.txt", []byte("second")),
+	})
+	if err != nil {
+		t.Fatalf("Can't create small lod archive: %v", err)
+	}
+	smallBytes, err := os.ReadFile(small.ArchiveFilePath)
+	if err != nil {
+		t.Fatalf("Can't read small lod archive: %v", err)
+	}
+
+	// rebuild the archive with reserved header bytes, unknown entry fields and 100 table slots,
+	// with garbage the original packer left after names and in unused slots in the second case
+	for _, garbage := range []bool{false, true} {
+		t.Run(fmt.Sprintf("garbage=%v", garbage), func(t *testing.T) {
+			testByteExactRoundTrip(t, smallBytes, garbage)
+		})
+	}
+}
+
+func testByteExactRoundTrip(t *testing.T, smallBytes []byte, garbage bool) {
+	const tableSlots, extraSlots = 100, 98
+	dataStart := 92 + 32*2
+	original := append([]byte{}, smallBytes[:dataStart]...)
+	copy(original[12:], []byte{1, 2, 3, 4})
+	for i := 0; i < 2; i++ {
+		entry := original[92+32*i:]
+		binary.LittleEndian.PutUint32(entry[16:], binary.LittleEndian.Uint32(entry[16:])+32*extraSlots)
+		binary.LittleEndian.PutUint32(entry[24:], uint32(0xA0+i))
+	}
+	unusedSlots := make([]byte, 32*extraSlots)
+	if garbage {
+		copy(original[92+len("first.txt")+1:92+16], "WAV\x01")
+		copy(unusedSlots[32*50:], "garbage.txt\x00WAV")
+	}
+	original = append(original, unusedSlots...)
+	original = append(original, smallBytes[dataStart:]...)
+	originalPath := filepath.Join(tempDirPath, fmt.Sprintf("original_%v.lod", garbage))
+	err := os.WriteFile(originalPath, original, 0600)
+	if err != nil {
+		t.Fatalf("Can't write original lod archive: %v", err)
+	}
+
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(originalPath)
+	if err != nil {
+		t.Fatalf("Can't load original lod archive: %v", err)
+	}
+	if lam.TableSize != tableSlots {
+		t.Errorf("Wrong table size: %d", lam.TableSize)
+	}
+	if lam.Files[1].Unknown != 0xA1 {
+		t.Errorf("Wrong unknown field: %x", lam.Files[1].Unknown)
+	}
+	if !bytes.Equal(lam.Reserved[:4], []byte{1, 2, 3, 4}) {
+		t.Errorf("Wrong reserved header: %x", lam.Reserved)
+	}
+	if lam.Files[0].Name != "first.txt" || (len(lam.Files[0].NameField) != 0) != garbage || (len(lam.UnusedSlots) != 0) != garbage {
+		t.Errorf("Wrong raw name field %x or unused slots of %s", lam.Files[0].NameField, lam.Files[0].Name)
+	}
+
+	err = lam.WriteJsonFile(tempDirPath, "original.json")
+	if err != nil {
+		t.Fatalf("Can't write lod archive meta json: %v", err)
+	}
+	fromJson, err := lodparse.LoadLodArchiveMetaFromJson(filepath.Join(tempDirPath, "original.json"))
+	if err != nil {
+		t.Fatalf("Can't load lod archive meta json: %v", err)
+	}
+	if !reflect.DeepEqual(lam.Files, fromJson.Files) || !bytes.Equal(lam.Reserved, fromJson.Reserved) ||
+		lam.TableSize != fromJson.TableSize || !bytes.Equal(lam.UnusedSlots, fromJson.UnusedSlots) {
+		t.Error("Lod archive meta has changed after json round trip")
+	}
+
+	reproducedPath := filepath.Join(tempDirPath, "reproduced.lod")
+	err = fromJson.Save(reproducedPath)
+	if err != nil {
+		t.Fatalf("Can't save lod archive: %v", err)
+	}
+	reproduced, err := os.ReadFile(reproducedPath)
+	if err != nil {
+		t.Fatalf("Can't read reproduced lod archive: %v", err)
+	}
+	if !bytes.Equal(original, reproduced) {
+		t.Error("Reproduced lod archive differs from original")
+	}
+}
+
+func TestExtractFileChecksSize(t *testing.T) {
+	packed := loadPackedTestLod(t)
+	lodBytes, err := os.ReadFile(packed.ArchiveFilePath)
+	if err != nil {
+		t.Fatalf("Can't read packed lod archive: %v", err)
+	}
+	dstDir := filepath.Join(tempDirPath, "size_checked")
+	err = os.Mkdir(dstDir, 0700)
+	if err != nil {
+		t.Fatalf("Can't create dir for extracted files: %v", err)
+	}
+
+	compressed, err := packed.GetFile("advevent.txt")
+	if err != nil {
+		t.Fatalf("Can't get file meta: %v", err)
+	}
+	notCompressed, err := packed.GetFile("AVArnd1.msk")
+	if err != nil {
+		t.Fatalf("Can't get file meta: %v", err)
+	}
+	lodReader := bytes.NewReader(lodBytes)
+	for _, file := range []lodparse.LodFileMeta{compressed, notCompressed} {
+		err = lodparse.ExtractFile(file, lodReader, dstDir)
+		if err != nil {
+			t.Fatalf("Can't extract file(%s): %v", file.Name, err)
+		}
+	}
+
+	for _, sizeDelta := range []int{-1, 1} {
+		wrongSize := compressed
+		wrongSize.Name = "wrong_" + compressed.Name
+		wrongSize.OriginalSize = uint32(int(compressed.OriginalSize) + sizeDelta)
+		err = lodparse.ExtractFile(wrongSize, lodReader, dstDir)
+		if err == nil {
+			t.Errorf("Expected error extracting compressed file with original size changed by %d", sizeDelta)
+		}
+		if _, err := os.Stat(filepath.Join(dstDir, wrongSize.Name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Partially extracted file(%s) is not removed", wrongSize.Name)
+		}
+	}
+
+	truncatedReader := bytes.NewReader(lodBytes[:notCompressed.Offset+notCompressed.OriginalSize/2])
+	err = lodparse.ExtractFile(notCompressed, truncatedReader, dstDir)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF extracting truncated file, got %v", err)
+	}
+}
+
+func TestReadFileChecksTableSizes(t *testing.T) {
+	packed := loadPackedTestLod(t)
+	lodBytes, err := os.ReadFile(packed.ArchiveFilePath)
+	if err != nil {
+		t.Fatalf("Can't read packed lod archive: %v", err)
+	}
+
+	for _, name := range []string{"advevent.txt", "AVArnd1.msk"} {
+		lam, err := lodparse.ParseLod(bytes.NewReader(lodBytes), int64(len(lodBytes)))
+		if err != nil {
+			t.Fatalf("Can't parse lod archive: %v", err)
+		}
+		for i := range lam.Files {
+			if lam.Files[i].Name == name {
+				lam.Files[i].OriginalSize = math.MaxUint32
+			}
+		}
+		_, err = lam.ReadFile(name)
+		if err == nil {
+			t.Errorf("Expected error reading file(%s) with corrupted original size", name)
+		}
+	}
+}
+
+func TestBuildLodArchive(t *testing.T) {
+	recipe, err := lodparse.LoadLodArchiveMetaFromJson(filepath.Join(".", "testdata", "HotA_lng.json"))
+	if err != nil {
+		t.Fatalf("Can't load lod recipe: %v", err)
+	}
+	srcDir := filepath.Join(".", "testdata", "HotA_lng_files")
+	builtLodPath := filepath.Join(tempDirPath, "built.lod")
+	_, err = lodparse.BuildLodArchive(recipe, srcDir, builtLodPath)
+	if err != nil {
+		t.Fatalf("Can't build lod archive: %v", err)
+	}
+
+	lam, err := lodparse.LoadLodArchiveMetaFromLodFile(builtLodPath)
+	if err != nil {
+		t.Fatalf("Can't load built lod archive meta: %v", err)
+	}
+	if lam.LodType != recipe.LodType || lam.NumberOfFiles != recipe.NumberOfFiles {
+		t.Fatalf("Built archive type %d with %d files, expected type %d with %d files", lam.LodType, lam.NumberOfFiles, recipe.LodType, recipe.NumberOfFiles)
+	}
+	if lam.Files[0].Offset != recipe.Files[0].Offset {
+		t.Errorf("Reserved table slots aren't kept, first offset is %d, expected %d", lam.Files[0].Offset, recipe.Files[0].Offset)
+	}
+	for i, file := range lam.Files {
+		expected := recipe.Files[i]
+		if file.Name != expected.Name || file.OriginalSize != expected.OriginalSize || file.IsCompressed() != expected.IsCompressed() {
+			t.Errorf("Built file %+v doesn't match recipe %+v", file, expected)
+		}
+	}
+	content, err := lam.ReadFile("AVArnd1.def")
+	if err != nil {
+		t.Fatalf("Can't read built file: %v", err)
+	}
+	original, err := os.ReadFile(filepath.Join(srcDir, "AVArnd1.def"))
+	if err != nil {
+		t.Fatalf("Can't read original file: %v", err)
+	}
+	if !bytes.Equal(content, original) {
+		t.Error("Built file differs from original")
+	}
+
+	recipe.Files = append(recipe.Files, lodparse.LodFileMeta{Name: "missing.txt"})
+	recipe.NumberOfFiles++
+	_, err = lodparse.BuildLodArchive(recipe, srcDir, filepath.Join(tempDirPath, "broken.lod"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Recipe with missing file is built: %v", err)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	first, err := lodparse.CreateLodArchive(filepath.Join(tempDirPath, "dups1.lod"), lodparse.Base, []lodparse.LodFileSource{
+		bytesSource("same.txt", []byte("shared content")),
+		bytesSource("copy.txt", []byte("shared content")),
+		bytesSource("mod.txt", []byte("original")),
+		bytesSource("unique.txt", []byte("unique content of the first archive")),
+	})
+	if err != nil {
+		t.Fatalf("Can't create first lod archive: %v", err)
+	}
+	second, err := lodparse.CreateLodArchive(filepath.Join(tempDirPath, "dups2.lod"), lodparse.Expansion, []lodparse.LodFileSource{
+		bytesSource("SAME.TXT", []byte("shared content")),
+		bytesSource("Mod.txt", []byte("modified")),
+	})
+	if err != nil {
+		t.Fatalf("Can't create second lod archive: %v", err)
+	}
+
+	report, err := lodparse.FindDuplicates([]*lodparse.LodArchiveMeta{first, second})
+	if err != nil {
+		t.Fatalf("Can't find duplicates: %v", err)
+	}
+	if len(report.Duplicates) != 1 || len(report.Duplicates[0].Files) != 3 {
+		t.Fatalf("Wrong duplicate groups: %+v", report.Duplicates)
+	}
+	if report.WastedBytes != int64(2*len("shared content")) {
+		t.Errorf("Wrong wasted bytes: %d", report.WastedBytes)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Name != "mod.txt" || len(report.Conflicts[0].Files) != 2 {
+		t.Errorf("Wrong name conflicts: %+v", report.Conflicts)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	identity, err := loadPackedTestLod(t).Identify()
+	if err != nil {
+		t.Fatalf("Can't identify lod archive: %v", err)
+	}
+	if identity.Product != lodparse.ProductHotA || !reflect.DeepEqual(identity.Evidence, []string{"has H3Horn.h3c", "lod type 500"}) {
+		t.Errorf("Wrong identity of HotA archive: %v", identity)
+	}
+
+	createAndIdentifyTyped := func(name string, lodType lodparse.LodArchiveType, fingerprints []lodparse.LodFingerprint, sources ...lodparse.LodFileSource) lodparse.LodIdentity {
+		t.Helper()
+		lam, err := lodparse.CreateLodArchive(filepath.Join(tempDirPath, name), lodType, sources)
+		if err != nil {
+			t.Fatalf("Can't create lod archive: %v", err)
+		}
+		identity, err := lam.IdentifyWith(fingerprints)
+		if err != nil {
+			t.Fatalf("Can't identify lod archive: %v", err)
+		}
+		return identity
+	}
+	createAndIdentify := func(name string, fingerprints []lodparse.LodFingerprint, sources ...lodparse.LodFileSource) lodparse.LodIdentity {
+		t.Helper()
+		return createAndIdentifyTyped(name, lodparse.Expansion, fingerprints, sources...)
+	}
+	conflux := bytesSource("TbElBack.pcx", []byte("conflux"))
+	combo := bytesSource("AVA0129.def", []byte("angelic alliance"))
+	if identity := createAndIdentify("ab.lod", lodparse.DefaultLodFingerprints, conflux); identity.Product != lodparse.ProductAB {
+		t.Errorf("Wrong identity of AB archive: %v", identity)
+	}
+	if identity := createAndIdentify("sod.lod", lodparse.DefaultLodFingerprints, conflux, combo); identity.Product != lodparse.ProductSoD {
+		t.Errorf("Wrong identity of SoD archive: %v", identity)
+	}
+	// SoD H3bitmap.lod has the Conflux town screen too
+	campaignSets := bytesSource("CAMPBKX2.pcx", []byte("campaign sets"))
+	if identity := createAndIdentify("sod_bitmap.lod", lodparse.DefaultLodFingerprints, conflux, campaignSets); identity.Product != lodparse.ProductSoD {
+		t.Errorf("Wrong identity of SoD bitmap archive: %v", identity)
+	}
+	if identity := createAndIdentify("unknown.lod", lodparse.DefaultLodFingerprints, bytesSource("a.txt", nil)); identity.Product != lodparse.ProductUnknown {
+		t.Errorf("Wrong identity of unknown archive: %v", identity)
+	}
+	// the type alone doesn't tell the product
+	if identity := createAndIdentifyTyped("typed.lod", 0x1F4, lodparse.DefaultLodFingerprints, conflux); identity.Product != lodparse.ProductAB {
+		t.Errorf("Wrong identity of AB archive of type 0x1F4: %v", identity)
+	}
+
+	// the Complete edition isn't identified by default, its products are told by fingerprints of the copy
+	const productComplete lodparse.LodProduct = "complete"
+	comboSHA := sha256.Sum256([]byte("angelic alliance"))
+	complete := lodparse.LodFingerprint{
+		Product: productComplete,
+		SHA256:  map[string]string{"ava0129.def": hex.EncodeToString(comboSHA[:])},
+	}
+	fingerprints := append([]lodparse.LodFingerprint{complete}, lodparse.DefaultLodFingerprints...)
+	if identity := createAndIdentify("complete.lod", fingerprints, conflux, combo); identity.Product != productComplete {
+		t.Errorf("Wrong identity of Complete archive: %v", identity)
+	}
+	redrawn := bytesSource("AVA0129.def", []byte("redrawn alliance"))
+	if identity := createAndIdentify("not_complete.lod", fingerprints, conflux, redrawn); identity.Product != lodparse.ProductSoD {
+		t.Errorf("Archive with different content is identified as %v", identity)
+	}
+}
+
+func TestLodArchiveTypeJSON(t *testing.T) {
+	for _, lodType := range []lodparse.LodArchiveType{lodparse.Base, lodparse.Expansion, 0xff, 0x1F4, 7} {
+		data, err := json.Marshal(lodType)
+		if err != nil {
+			t.Fatalf("Can't marshal lod type %d: %v", lodType, err)
+		}
+		var decoded lodparse.LodArchiveType
+		err = json.Unmarshal(data, &decoded)
+		if err != nil || decoded != lodType {
+			t.Errorf("Lod type %d is decoded from %s as %d: %v", lodType, data, decoded, err)
+		}
+	}
+	if data, _ := json.Marshal(lodparse.Expansion); string(data) != `"expansion"` {
+		t.Errorf("Wrong json name of expansion type: %s", data)
+	}
+	if data, _ := json.Marshal(lodparse.LodArchiveType(0x1F4)); string(data) != "500" {
+		t.Errorf("Unknown lod type is encoded as %s", data)
+	}
+
+	var decoded lodparse.LodArchiveType
+	if err := json.Unmarshal([]byte("2"), &decoded); err != nil || decoded != lodparse.Expansion {
+		t.Errorf("Numeric lod type is decoded as %d: %v", decoded, err)
+	}
+	if err := json.Unmarshal([]byte(`"nope"`), &decoded); err == nil {
+		t.Error("Unknown lod type name is decoded")
+	}
+}
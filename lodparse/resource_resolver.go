@@ -0,0 +1,182 @@
+package lodparse
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ResourceLayer is a single source of resources, either a lod archive or a loose directory
+type ResourceLayer struct {
+	Archive *LodArchiveMeta
+	Dir     string
+}
+
+func (rl *ResourceLayer) String() string {
+	if rl.Archive != nil {
+		return rl.Archive.ArchiveFilePath
+	}
+	return rl.Dir
+}
+
+// ResolvedResource tells which layer supplies a resource
+type ResolvedResource struct {
+	Name  string
+	Size  int64
+	Layer *ResourceLayer
+	// File is set only for resources supplied by archives
+	File LodFileMeta
+}
+
+// ShadowedResource is a resource supplied by several layers, only the Winner is seen by the game
+type ShadowedResource struct {
+	Winner   ResolvedResource
+	Shadowed []ResolvedResource
+}
+
+// ResourceResolver stacks archives and loose directories in priority order.
+// Layers added later override earlier ones and names are resolved ignoring case, like the game does.
+type ResourceResolver struct {
+	layers    []*ResourceLayer
+	resources map[string][]ResolvedResource
+}
+
+func NewResourceResolver() *ResourceResolver {
+	return &ResourceResolver{
+		resources: make(map[string][]ResolvedResource),
+	}
+}
+
+// AddArchive puts lam on top of already added layers
+func (rr *ResourceResolver) AddArchive(lam *LodArchiveMeta) {
+	layer := &ResourceLayer{Archive: lam}
+	rr.layers = append(rr.layers, layer)
+
+	seen := make(map[string]bool, len(lam.Files))
+	for _, file := range lam.Files {
+		folded := foldName(file.Name)
+		if seen[folded] {
+			continue
+		}
+		seen[folded] = true
+		rr.resources[folded] = append(rr.resources[folded], ResolvedResource{
+			Name:  file.Name,
+			Size:  int64(file.OriginalSize),
+			Layer: layer,
+			File:  file,
+		})
+	}
+}
+
+// AddLodFile loads lod archive meta from pathToLod and puts it on top of already added layers
+func (rr *ResourceResolver) AddLodFile(pathToLod string) error {
+	lam, err := LoadLodArchiveMetaFromLodFile(pathToLod)
+	if err != nil {
+		return err
+	}
+	rr.AddArchive(lam)
+	return nil
+}
+
+// AddDir puts regular files of dir on top of already added layers.
+// The directory content is read once, files added to it later are not seen by the resolver.
+func (rr *ResourceResolver) AddDir(dir string) error {
+	dirContent, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("can't read resources dir(%s): %w", dir, err)
+	}
+
+	layer := &ResourceLayer{Dir: dir}
+	rr.layers = append(rr.layers, layer)
+	for _, entry := range dirContent {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("can't stat resource(%s): %w", entry.Name(), err)
+		}
+		folded := foldName(entry.Name())
+		rr.resources[folded] = append(rr.resources[folded], ResolvedResource{
+			Name:  entry.Name(),
+			Size:  info.Size(),
+			Layer: layer,
+		})
+	}
+	return nil
+}
+
+// Layers returns layers from the lowest to the highest priority
+func (rr *ResourceResolver) Layers() []*ResourceLayer {
+	return rr.layers
+}
+
+// Resolve tells which layer supplies the named resource
+func (rr *ResourceResolver) Resolve(name string) (ResolvedResource, error) {
+	candidates := rr.resources[foldName(name)]
+	if len(candidates) == 0 {
+		return ResolvedResource{}, fmt.Errorf("can't resolve resource(%s): %w", name, errNoSuchFile)
+	}
+	return candidates[len(candidates)-1], nil
+}
+
+// Resources returns all resources seen by the game ordered by name
+func (rr *ResourceResolver) Resources() []ResolvedResource {
+	resources := make([]ResolvedResource, 0, len(rr.resources))
+	for _, candidates := range rr.resources {
+		resources = append(resources, candidates[len(candidates)-1])
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return foldName(resources[i].Name) < foldName(resources[j].Name)
+	})
+	return resources
+}
+
+// Shadowed returns resources supplied by more than one layer ordered by name
+func (rr *ResourceResolver) Shadowed() []ShadowedResource {
+	var shadowed []ShadowedResource
+	for _, candidates := range rr.resources {
+		if len(candidates) < 2 {
+			continue
+		}
+		shadowed = append(shadowed, ShadowedResource{
+			Winner:   candidates[len(candidates)-1],
+			Shadowed: candidates[:len(candidates)-1],
+		})
+	}
+	sort.Slice(shadowed, func(i, j int) bool {
+		return foldName(shadowed[i].Winner.Name) < foldName(shadowed[j].Winner.Name)
+	})
+	return shadowed
+}
+
+// Open returns a reader of the winning content of the named resource
+func (rr *ResourceResolver) Open(name string) (io.ReadCloser, error) {
+	resource, err := rr.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Open()
+}
+
+// ReadFile returns the winning content of the named resource
+func (rr *ResourceResolver) ReadFile(name string) ([]byte, error) {
+	resource, err := rr.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if resource.Layer.Archive != nil {
+		return resource.Layer.Archive.readFile(resource.File)
+	}
+	return os.ReadFile(filepath.Join(resource.Layer.Dir, resource.Name))
+}
+
+// Open returns a reader of the resource content in its layer
+func (rr ResolvedResource) Open() (io.ReadCloser, error) {
+	if rr.Layer.Archive != nil {
+		return rr.Layer.Archive.openFile(rr.File)
+	}
+	return os.Open(filepath.Join(rr.Layer.Dir, rr.Name))
+}
@@ -0,0 +1,97 @@
+package pcxparse
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/netscrn/homm3utils/internal/binread"
+)
+
+// maxPcxPixels limits dimensions read from the header, so a corrupted header doesn't exhaust memory
+const maxPcxPixels = 1 << 26
+
+type PcxMeta struct {
+	Size   uint32
+	Width  uint32
+	Height uint32
+}
+
+// DecodePcx decodes pcx images used by the game (not the ZSoft PCX format).
+// They are either 8-bit paletted with the palette following pixels or 24-bit with BGR pixels.
+func DecodePcx(r io.Reader) (image.Image, error) {
+	pcxMeta, err := readPcxMeta(r)
+	if err != nil {
+		return nil, fmt.Errorf("can't read pcx header: %w", err)
+	}
+
+	pixelsCount := uint64(pcxMeta.Width) * uint64(pcxMeta.Height)
+	if pixelsCount > maxPcxPixels {
+		return nil, fmt.Errorf("pcx dimensions(%dx%d) are too large", pcxMeta.Width, pcxMeta.Height)
+	}
+	rect := image.Rect(0, 0, int(pcxMeta.Width), int(pcxMeta.Height))
+
+	switch uint64(pcxMeta.Size) {
+	case pixelsCount:
+		return decodePalettedPcx(r, rect)
+	case pixelsCount * 3:
+		return decodeBGRPcx(r, rect)
+	default:
+		return nil, errors.New("unknown pcx format, size doesn't match dimensions")
+	}
+}
+
+func readPcxMeta(r io.Reader) (*PcxMeta, error) {
+	var pcxMeta PcxMeta
+	err := binread.ReadUint32(r, &pcxMeta.Size)
+	if err != nil {
+		return nil, fmt.Errorf("can't read Size: %w", err)
+	}
+	err = binread.ReadUint32(r, &pcxMeta.Width)
+	if err != nil {
+		return nil, fmt.Errorf("can't read Width: %w", err)
+	}
+	err = binread.ReadUint32(r, &pcxMeta.Height)
+	if err != nil {
+		return nil, fmt.Errorf("can't read Height: %w", err)
+	}
+	return &pcxMeta, nil
+}
+
+func decodePalettedPcx(r io.Reader, rect image.Rectangle) (*image.Paletted, error) {
+	img := image.NewPaletted(rect, nil)
+	_, err := io.ReadFull(r, img.Pix)
+	if err != nil {
+		return nil, fmt.Errorf("can't read pcx pixels: %w", err)
+	}
+
+	rgb := make([]byte, 256*3)
+	_, err = io.ReadFull(r, rgb)
+	if err != nil {
+		return nil, fmt.Errorf("can't read pcx palette: %w", err)
+	}
+	img.Palette = make(color.Palette, 256)
+	for i := range img.Palette {
+		img.Palette[i] = color.RGBA{R: rgb[i*3], G: rgb[i*3+1], B: rgb[i*3+2], A: 255}
+	}
+	return img, nil
+}
+
+func decodeBGRPcx(r io.Reader, rect image.Rectangle) (*image.RGBA, error) {
+	bgr := make([]byte, rect.Dx()*rect.Dy()*3)
+	_, err := io.ReadFull(r, bgr)
+	if err != nil {
+		return nil, fmt.Errorf("can't read pcx pixels: %w", err)
+	}
+
+	img := image.NewRGBA(rect)
+	for i := 0; i < rect.Dx()*rect.Dy(); i++ {
+		img.Pix[i*4] = bgr[i*3+2]
+		img.Pix[i*4+1] = bgr[i*3+1]
+		img.Pix[i*4+2] = bgr[i*3]
+		img.Pix[i*4+3] = 255
+	}
+	return img, nil
+}
@@ -0,0 +1,103 @@
+package pcxparse_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/netscrn/homm3utils/pcxparse"
+)
+
+// pcx images of the lodparse test archive are used, pcxparse has no own test data
+var testPcxDir = filepath.Join("..", "lodparse", "testdata", "HotA_lng_files")
+
+func decodeTestPcx(t *testing.T, name string) image.Image {
+	t.Helper()
+	pcxFile, err := os.Open(filepath.Join(testPcxDir, name))
+	if err != nil {
+		t.Fatalf("Can't open pcx: %v", err)
+	}
+	defer pcxFile.Close()
+	img, err := pcxparse.DecodePcx(pcxFile)
+	if err != nil {
+		t.Fatalf("Can't decode pcx(%s): %v", name, err)
+	}
+	return img
+}
+
+func TestDecodePcx(t *testing.T) {
+	dirContent, err := os.ReadDir(testPcxDir)
+	if err != nil {
+		t.Fatalf("Can't read test pcx dir: %v", err)
+	}
+	decoded := 0
+	for _, entry := range dirContent {
+		if !strings.EqualFold(filepath.Ext(entry.Name()), ".pcx") {
+			continue
+		}
+		img := decodeTestPcx(t, entry.Name())
+		if img.Bounds().Empty() {
+			t.Errorf("Pcx(%s) is empty", entry.Name())
+		}
+		decoded++
+	}
+	if decoded != 22 {
+		t.Errorf("Expected 22 decoded pcx images, got %d", decoded)
+	}
+}
+
+func TestDecodePcxPixels(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		paletted      bool
+		x, y          int
+		expected      color.RGBA
+	}{
+		{name: "AdvOpts.pcx", width: 289, height: 387, paletted: true, x: 0, y: 0, expected: color.RGBA{R: 16, G: 0, B: 16, A: 255}},
+		{name: "AdvOpts.pcx", width: 289, height: 387, paletted: true, x: 144, y: 193, expected: color.RGBA{R: 66, G: 41, B: 24, A: 255}},
+		{name: "icm0110.pcx", width: 199, height: 36, paletted: true, x: 100, y: 18, expected: color.RGBA{R: 33, G: 24, B: 14, A: 255}},
+		{name: "GamSelBk.pcx", width: 800, height: 600, x: 0, y: 0, expected: color.RGBA{R: 42, G: 49, B: 48, A: 255}},
+		{name: "GamSelBk.pcx", width: 800, height: 600, x: 400, y: 300, expected: color.RGBA{R: 92, G: 97, B: 85, A: 255}},
+		{name: "GamSelBk.pcx", width: 800, height: 600, x: 799, y: 599, expected: color.RGBA{R: 54, G: 65, B: 47, A: 255}},
+	}
+	for _, test := range tests {
+		img := decodeTestPcx(t, test.name)
+		if img.Bounds() != image.Rect(0, 0, test.width, test.height) {
+			t.Errorf("Pcx(%s) is %v, expected %dx%d", test.name, img.Bounds(), test.width, test.height)
+		}
+		if _, paletted := img.(*image.Paletted); paletted != test.paletted {
+			t.Errorf("Pcx(%s) is decoded as %T", test.name, img)
+		}
+		if c := color.RGBAModel.Convert(img.At(test.x, test.y)); c != test.expected {
+			t.Errorf("Pixel(%d, %d) of pcx(%s) is %v, expected %v", test.x, test.y, test.name, c, test.expected)
+		}
+	}
+}
+
+func TestDecodePcxRejectsBadHeader(t *testing.T) {
+	header := func(size, width, height uint32) []byte {
+		b := make([]byte, 12)
+		binary.LittleEndian.PutUint32(b[0:4], size)
+		binary.LittleEndian.PutUint32(b[4:8], width)
+		binary.LittleEndian.PutUint32(b[8:12], height)
+		return b
+	}
+	tests := map[string][]byte{
+		"truncated header": header(4, 2, 2)[:8],
+		"wrong size":       header(5, 2, 2),
+		"huge dimensions":  header(0, 1<<20, 1<<20),
+		"truncated pixels": append(header(4, 2, 2), 1, 2),
+	}
+	for name, pcx := range tests {
+		_, err := pcxparse.DecodePcx(bytes.NewReader(pcx))
+		if err == nil {
+			t.Errorf("Expected error decoding pcx with %s", name)
+		}
+	}
+}
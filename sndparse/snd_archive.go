@@ -0,0 +1,29 @@
+package sndparse
+
+import (
+	"github.com/netscrn/homm3utils/internal/rawarchive"
+)
+
+const sndKind = "snd"
+
+func LoadSndArchiveMetaFromJson(pathToJson string) (*SndArchiveMeta, error) {
+	archive, err := rawarchive.LoadJson(sndKind, pathToJson)
+	if err != nil {
+		return nil, err
+	}
+	return &SndArchiveMeta{Archive: archive}, nil
+}
+
+func LoadSndArchiveMetaFromSndFile(pathToSnd string) (*SndArchiveMeta, error) {
+	return parseSndFile(pathToSnd)
+}
+
+// SndArchiveMeta describes a snd archive, sound files are stored in it without compression.
+// Files are looked up, read, extracted and served as fs.FS the same way as files of vid archives.
+type SndArchiveMeta struct {
+	rawarchive.Archive
+}
+
+// SndFileMeta is a sound file of the archive. Its name is stored as a base name and an extension
+// separated by null, they are joined with a dot.
+type SndFileMeta = rawarchive.File
@@ -0,0 +1,129 @@
+package sndparse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/netscrn/homm3utils/internal/binread"
+	"github.com/netscrn/homm3utils/internal/rawarchive"
+)
+
+const (
+	sndHeaderSize    = 4
+	sndFileNameSize  = 40
+	sndFileEntrySize = sndFileNameSize + 8
+	sndExtensionSize = 3
+)
+
+// ParseSnd reads snd archive meta from r, size is the size of the whole archive.
+// The returned meta keeps r to read archive files, so r should stay readable while the meta is in use.
+func ParseSnd(r io.ReaderAt, size int64) (*SndArchiveMeta, error) {
+	files, err := parseSnd(r, size, "")
+	if err != nil {
+		return nil, err
+	}
+	return &SndArchiveMeta{Archive: rawarchive.New(sndKind, "", files, r)}, nil
+}
+
+func parseSndFile(pathToSnd string) (*SndArchiveMeta, error) {
+	sndFile, err := os.Open(pathToSnd)
+	if err != nil {
+		return nil, fmt.Errorf("can't open snd archive(%s): %w", pathToSnd, err)
+	}
+	defer sndFile.Close()
+
+	sndFileInfo, err := sndFile.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("can't stat snd archive(%s): %w", pathToSnd, err)
+	}
+
+	files, err := parseSnd(sndFile, sndFileInfo.Size(), pathToSnd)
+	if err != nil {
+		return nil, err
+	}
+	return &SndArchiveMeta{Archive: rawarchive.New(sndKind, pathToSnd, files, nil)}, nil
+}
+
+// parseSnd reads the files table, the archive meta is created from it by callers
+func parseSnd(r io.ReaderAt, size int64, pathToSnd string) ([]SndFileMeta, error) {
+	sndFileReader := io.NewSectionReader(r, 0, size)
+
+	var numberOfFiles uint32
+	err := binread.ReadUint32(sndFileReader, &numberOfFiles)
+	if err != nil {
+		return nil, fmt.Errorf("can't read numberOfFiles of snd archive(%s): %w", pathToSnd, err)
+	}
+	if numberOfFiles == 0 {
+		return nil, errors.New("snd archive is empty")
+	}
+	if int64(sndHeaderSize)+int64(sndFileEntrySize)*int64(numberOfFiles) > size {
+		return nil, fmt.Errorf("snd archive(%s) is too small for %d files", pathToSnd, numberOfFiles)
+	}
+
+	files, err := readSndFiles(sndFileReader, numberOfFiles)
+	if err != nil {
+		return nil, fmt.Errorf("can't read files of snd archive(%s): %w", pathToSnd, err)
+	}
+	for _, file := range files {
+		if int64(file.Offset)+int64(file.Size) > size {
+			return nil, fmt.Errorf("snd file(%s) content is outside of snd archive(%s)", file.Name, pathToSnd)
+		}
+	}
+
+	return files, nil
+}
+
+func readSndFiles(saf io.Reader, numberOfFiles uint32) ([]SndFileMeta, error) {
+	sndFiles := make([]SndFileMeta, 0, numberOfFiles)
+
+	nameBuf := make([]byte, sndFileNameSize)
+	var fi uint32
+	for fi = 0; fi < numberOfFiles; fi++ {
+		sndFile := SndFileMeta{}
+
+		_, err := io.ReadFull(saf, nameBuf)
+		if err != nil {
+			return nil, fmt.Errorf("error reading name of [%d]: %w", fi, err)
+		}
+		sndFile.Name = sndFileName(nameBuf)
+		if !rawarchive.ValidName(sndFile.Name) {
+			return nil, fmt.Errorf("invalid name(%q) of [%d]", sndFile.Name, fi)
+		}
+
+		err = binread.ReadUint32(saf, &sndFile.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("error reading offset of [%d, %s]: %w", fi, sndFile.Name, err)
+		}
+		err = binread.ReadUint32(saf, &sndFile.Size)
+		if err != nil {
+			return nil, fmt.Errorf("error reading size of [%d, %s]: %w", fi, sndFile.Name, err)
+		}
+
+		sndFiles = append(sndFiles, sndFile)
+	}
+
+	return sndFiles, nil
+}
+
+// sndFileName joins the base name and the extension following its terminating null, like "NAME\x00WAV" to "NAME.WAV".
+// Bytes after the extension are garbage left by the original packer.
+func sndFileName(nameBuf []byte) string {
+	baseEnd := bytes.IndexByte(nameBuf, 0)
+	if baseEnd == -1 {
+		return string(nameBuf)
+	}
+	base, ext := nameBuf[:baseEnd], nameBuf[baseEnd+1:]
+	if len(ext) > sndExtensionSize {
+		ext = ext[:sndExtensionSize]
+	}
+	if extEnd := bytes.IndexByte(ext, 0); extEnd != -1 {
+		ext = ext[:extEnd]
+	}
+	if len(ext) == 0 {
+		return string(base)
+	}
+	return string(base) + "." + string(ext)
+}
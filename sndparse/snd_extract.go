@@ -0,0 +1,37 @@
+package sndparse
+
+import (
+	"context"
+	"io"
+
+	"github.com/netscrn/homm3utils/internal/rawarchive"
+)
+
+// ExtractOptions configures ExtractSndFilesContext
+type ExtractOptions = rawarchive.ExtractOptions
+
+// ExtractProgress is reported to ExtractOptions.Progress after each extracted file
+type ExtractProgress = rawarchive.ExtractProgress
+
+// ExtractFileError is an error of a single snd file extraction
+type ExtractFileError = rawarchive.ExtractFileError
+
+// ExtractErrors aggregates errors of all files that failed during extraction
+type ExtractErrors = rawarchive.ExtractErrors
+
+func ExtractSndFiles(sndArchive *SndArchiveMeta, dstDir string, concurrencyLevel int) error {
+	return ExtractSndFilesContext(context.Background(), sndArchive, dstDir, ExtractOptions{
+		ConcurrencyLevel: concurrencyLevel,
+	})
+}
+
+// ExtractSndFilesContext extracts snd archive files into dstDir.
+// Failed files are returned as ExtractErrors, ctx.Err() is returned when extraction was cancelled without failures.
+func ExtractSndFilesContext(ctx context.Context, sndArchive *SndArchiveMeta, dstDir string, opts ExtractOptions) error {
+	return rawarchive.Extract(ctx, &sndArchive.Archive, dstDir, opts)
+}
+
+// ExtractFile copies a single file from the archive read by sndReader into dstDir
+func ExtractFile(file SndFileMeta, sndReader io.ReaderAt, dstDir string) error {
+	return rawarchive.ExtractFile(file, sndReader, dstDir)
+}
@@ -0,0 +1,165 @@
+package sndparse_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/netscrn/homm3utils/sndparse"
+)
+
+var testSounds = []struct {
+	rawName string
+	name    string
+	content []byte
+}{
+	{"AAGSAttack\x00wav\x00\x13\x37", "AAGSAttack.wav", []byte("RIFF attack sound")},
+	{"BUILDTWN\x00WAV", "BUILDTWN.WAV", []byte("RIFF build sound, a bit longer")},
+	{"NoExt", "NoExt", []byte{}},
+}
+
+// buildTestSnd lays out entries the way the original packer does: the table first and contents after it
+func buildTestSnd(t *testing.T) []byte {
+	t.Helper()
+	var table, content bytes.Buffer
+	binary.Write(&table, binary.LittleEndian, uint32(len(testSounds)))
+	offset := 4 + 48*len(testSounds)
+	for _, sound := range testSounds {
+		name := make([]byte, 40)
+		copy(name, sound.rawName)
+		table.Write(name)
+		binary.Write(&table, binary.LittleEndian, uint32(offset+content.Len()))
+		binary.Write(&table, binary.LittleEndian, uint32(len(sound.content)))
+		content.Write(sound.content)
+	}
+	return append(table.Bytes(), content.Bytes()...)
+}
+
+func writeTestSnd(t *testing.T) string {
+	t.Helper()
+	pathToSnd := filepath.Join(t.TempDir(), "test.snd")
+	err := os.WriteFile(pathToSnd, buildTestSnd(t), 0644)
+	if err != nil {
+		t.Fatalf("Can't write test snd archive: %v", err)
+	}
+	return pathToSnd
+}
+
+func TestLoadSndArchiveMetaFromSndFile(t *testing.T) {
+	sam, err := sndparse.LoadSndArchiveMetaFromSndFile(writeTestSnd(t))
+	if err != nil {
+		t.Fatalf("Can't load snd archive meta: %v", err)
+	}
+	if int(sam.NumberOfFiles) != len(testSounds) || len(sam.Files) != len(testSounds) {
+		t.Fatalf("Wrong number of files: %d", sam.NumberOfFiles)
+	}
+	for i, sound := range testSounds {
+		if sam.Files[i].Name != sound.name {
+			t.Errorf("Wrong name of file [%d]: %q, expected %q", i, sam.Files[i].Name, sound.name)
+		}
+		if int(sam.Files[i].Size) != len(sound.content) {
+			t.Errorf("Wrong size of file(%s): %d", sound.name, sam.Files[i].Size)
+		}
+	}
+
+	content, err := sam.ReadFile("aagsattack.WAV")
+	if err != nil {
+		t.Fatalf("Can't read file ignoring case: %v", err)
+	}
+	if !bytes.Equal(content, testSounds[0].content) {
+		t.Errorf("Wrong file content: %q", content)
+	}
+	if _, err := sam.GetFile("missing.wav"); err == nil {
+		t.Error("Missing file is found")
+	}
+}
+
+func TestParseSndRejectsTruncatedArchive(t *testing.T) {
+	snd := buildTestSnd(t)
+	_, err := sndparse.ParseSnd(bytes.NewReader(snd[:len(snd)-1]), int64(len(snd)-1))
+	if err == nil {
+		t.Error("Archive with truncated content is parsed")
+	}
+	_, err = sndparse.ParseSnd(bytes.NewReader(snd[:60]), 60)
+	if err == nil {
+		t.Error("Archive with truncated table is parsed")
+	}
+}
+
+func TestParseSndRejectsUnsafeNames(t *testing.T) {
+	for _, rawName := range []string{"..", "../evil\x00wav", "..\\evil\x00wav", "\x00"} {
+		snd := buildTestSnd(t)
+		copy(snd[4:44], make([]byte, 40))
+		copy(snd[4:44], rawName)
+		_, err := sndparse.ParseSnd(bytes.NewReader(snd), int64(len(snd)))
+		if err == nil {
+			t.Errorf("Archive with file name %q is parsed", rawName)
+		}
+	}
+}
+
+func TestSndArchiveMetaFS(t *testing.T) {
+	snd := buildTestSnd(t)
+	sam, err := sndparse.ParseSnd(bytes.NewReader(snd), int64(len(snd)))
+	if err != nil {
+		t.Fatalf("Can't parse snd archive: %v", err)
+	}
+	names := make([]string, 0, len(testSounds))
+	for _, sound := range testSounds {
+		names = append(names, sound.name)
+	}
+	err = fstest.TestFS(sam.FS(), names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractSndFiles(t *testing.T) {
+	sam, err := sndparse.LoadSndArchiveMetaFromSndFile(writeTestSnd(t))
+	if err != nil {
+		t.Fatalf("Can't load snd archive meta: %v", err)
+	}
+	dstDir := t.TempDir()
+	err = sndparse.ExtractSndFiles(sam, dstDir, 2)
+	if err != nil {
+		t.Fatalf("Can't extract snd archive: %v", err)
+	}
+	for _, sound := range testSounds {
+		content, err := os.ReadFile(filepath.Join(dstDir, sound.name))
+		if err != nil {
+			t.Fatalf("Can't read extracted file: %v", err)
+		}
+		if !bytes.Equal(content, sound.content) {
+			t.Errorf("Extracted file(%s) differs from original", sound.name)
+		}
+	}
+}
+
+func TestSndArchiveMetaJSON(t *testing.T) {
+	pathToSnd := writeTestSnd(t)
+	sam, err := sndparse.LoadSndArchiveMetaFromSndFile(pathToSnd)
+	if err != nil {
+		t.Fatalf("Can't load snd archive meta: %v", err)
+	}
+	jsonDir := t.TempDir()
+	err = sam.WriteJsonFile(jsonDir, "test.json")
+	if err != nil {
+		t.Fatalf("Can't write snd archive meta json: %v", err)
+	}
+
+	loaded, err := sndparse.LoadSndArchiveMetaFromJson(filepath.Join(jsonDir, "test.json"))
+	if err != nil {
+		t.Fatalf("Can't load snd archive meta from json: %v", err)
+	}
+	if loaded.ArchiveFilePath != pathToSnd || !reflect.DeepEqual(loaded.Files, sam.Files) {
+		t.Errorf("Loaded meta differs from written one: %+v", loaded)
+	}
+	content, err := loaded.ReadFile("BUILDTWN.WAV")
+	if err != nil || !bytes.Equal(content, testSounds[1].content) {
+		t.Errorf("Can't read file with meta loaded from json: %v", err)
+	}
+}
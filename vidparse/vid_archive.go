@@ -0,0 +1,29 @@
+package vidparse
+
+import (
+	"github.com/netscrn/homm3utils/internal/rawarchive"
+)
+
+const vidKind = "vid"
+
+func LoadVidArchiveMetaFromJson(pathToJson string) (*VidArchiveMeta, error) {
+	archive, err := rawarchive.LoadJson(vidKind, pathToJson)
+	if err != nil {
+		return nil, err
+	}
+	return &VidArchiveMeta{Archive: archive}, nil
+}
+
+func LoadVidArchiveMetaFromVidFile(pathToVid string) (*VidArchiveMeta, error) {
+	return parseVidFile(pathToVid)
+}
+
+// VidArchiveMeta describes a vid archive of smacker and bink videos, they are stored without compression.
+// Files are looked up, read, extracted and served as fs.FS the same way as files of snd archives.
+type VidArchiveMeta struct {
+	rawarchive.Archive
+}
+
+// VidFileMeta is a video file of the archive. Its size isn't stored in the archive,
+// it's the distance to the next file content or to the archive end.
+type VidFileMeta = rawarchive.File
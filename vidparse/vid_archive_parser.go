@@ -0,0 +1,143 @@
+package vidparse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/netscrn/homm3utils/internal/binread"
+	"github.com/netscrn/homm3utils/internal/rawarchive"
+)
+
+const (
+	vidHeaderSize    = 4
+	vidFileNameSize  = 40
+	vidFileEntrySize = vidFileNameSize + 4
+)
+
+// ParseVid reads vid archive meta from r, size is the size of the whole archive.
+// The returned meta keeps r to read archive files, so r should stay readable while the meta is in use.
+func ParseVid(r io.ReaderAt, size int64) (*VidArchiveMeta, error) {
+	files, err := parseVid(r, size, "")
+	if err != nil {
+		return nil, err
+	}
+	return &VidArchiveMeta{Archive: rawarchive.New(vidKind, "", files, r)}, nil
+}
+
+func parseVidFile(pathToVid string) (*VidArchiveMeta, error) {
+	vidFile, err := os.Open(pathToVid)
+	if err != nil {
+		return nil, fmt.Errorf("can't open vid archive(%s): %w", pathToVid, err)
+	}
+	defer vidFile.Close()
+
+	vidFileInfo, err := vidFile.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("can't stat vid archive(%s): %w", pathToVid, err)
+	}
+
+	files, err := parseVid(vidFile, vidFileInfo.Size(), pathToVid)
+	if err != nil {
+		return nil, err
+	}
+	return &VidArchiveMeta{Archive: rawarchive.New(vidKind, pathToVid, files, nil)}, nil
+}
+
+// parseVid reads the files table, the archive meta is created from it by callers
+func parseVid(r io.ReaderAt, size int64, pathToVid string) ([]VidFileMeta, error) {
+	vidFileReader := io.NewSectionReader(r, 0, size)
+
+	var numberOfFiles uint32
+	err := binread.ReadUint32(vidFileReader, &numberOfFiles)
+	if err != nil {
+		return nil, fmt.Errorf("can't read numberOfFiles of vid archive(%s): %w", pathToVid, err)
+	}
+	if numberOfFiles == 0 {
+		return nil, errors.New("vid archive is empty")
+	}
+	tableEnd := int64(vidHeaderSize) + int64(vidFileEntrySize)*int64(numberOfFiles)
+	if tableEnd > size {
+		return nil, fmt.Errorf("vid archive(%s) is too small for %d files", pathToVid, numberOfFiles)
+	}
+	if size > 0xffffffff {
+		return nil, fmt.Errorf("vid archive(%s) exceeds 4GB", pathToVid)
+	}
+
+	files, err := readVidFiles(vidFileReader, numberOfFiles)
+	if err != nil {
+		return nil, fmt.Errorf("can't read files of vid archive(%s): %w", pathToVid, err)
+	}
+	for _, file := range files {
+		if int64(file.Offset) < tableEnd || int64(file.Offset) > size {
+			return nil, fmt.Errorf("vid file(%s) offset %d is outside of vid archive(%s) content", file.Name, file.Offset, pathToVid)
+		}
+	}
+	setVidFileSizes(files, uint32(size))
+
+	return files, nil
+}
+
+func readVidFiles(vaf io.Reader, numberOfFiles uint32) ([]VidFileMeta, error) {
+	vidFiles := make([]VidFileMeta, 0, numberOfFiles)
+
+	nameBuf := make([]byte, vidFileNameSize)
+	var fi uint32
+	for fi = 0; fi < numberOfFiles; fi++ {
+		vidFile := VidFileMeta{}
+
+		_, err := io.ReadFull(vaf, nameBuf)
+		if err != nil {
+			return nil, fmt.Errorf("error reading name of [%d]: %w", fi, err)
+		}
+		name := vidFileName(nameBuf)
+		if !rawarchive.ValidName(name) {
+			return nil, fmt.Errorf("invalid name(%q) of [%d]", name, fi)
+		}
+		vidFile.Name = name
+
+		err = binread.ReadUint32(vaf, &vidFile.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("error reading offset of [%d, %s]: %w", fi, name, err)
+		}
+
+		vidFiles = append(vidFiles, vidFile)
+	}
+
+	return vidFiles, nil
+}
+
+// vidFileName returns the name up to the terminating null, a name taking the whole field has no terminator
+func vidFileName(nameBuf []byte) string {
+	nameEnd := bytes.IndexByte(nameBuf, 0)
+	if nameEnd == -1 {
+		return string(nameBuf)
+	}
+	return string(nameBuf[:nameEnd])
+}
+
+// setVidFileSizes derives sizes from offsets, because the archive doesn't store them.
+// Each file spans up to the next greater offset, the last one spans up to the archive end.
+func setVidFileSizes(files []VidFileMeta, archiveSize uint32) {
+	offsets := make([]uint32, 0, len(files))
+	for _, file := range files {
+		offsets = append(offsets, file.Offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i] < offsets[j]
+	})
+
+	for i := range files {
+		next := sort.Search(len(offsets), func(j int) bool {
+			return offsets[j] > files[i].Offset
+		})
+		end := archiveSize
+		if next < len(offsets) {
+			end = offsets[next]
+		}
+		files[i].Size = end - files[i].Offset
+	}
+}
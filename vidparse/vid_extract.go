@@ -0,0 +1,37 @@
+package vidparse
+
+import (
+	"context"
+	"io"
+
+	"github.com/netscrn/homm3utils/internal/rawarchive"
+)
+
+// ExtractOptions configures ExtractVidFilesContext
+type ExtractOptions = rawarchive.ExtractOptions
+
+// ExtractProgress is reported to ExtractOptions.Progress after each extracted file
+type ExtractProgress = rawarchive.ExtractProgress
+
+// ExtractFileError is an error of a single vid file extraction
+type ExtractFileError = rawarchive.ExtractFileError
+
+// ExtractErrors aggregates errors of all files that failed during extraction
+type ExtractErrors = rawarchive.ExtractErrors
+
+func ExtractVidFiles(vidArchive *VidArchiveMeta, dstDir string, concurrencyLevel int) error {
+	return ExtractVidFilesContext(context.Background(), vidArchive, dstDir, ExtractOptions{
+		ConcurrencyLevel: concurrencyLevel,
+	})
+}
+
+// ExtractVidFilesContext extracts vid archive files into dstDir.
+// Failed files are returned as ExtractErrors, ctx.Err() is returned when extraction was cancelled without failures.
+func ExtractVidFilesContext(ctx context.Context, vidArchive *VidArchiveMeta, dstDir string, opts ExtractOptions) error {
+	return rawarchive.Extract(ctx, &vidArchive.Archive, dstDir, opts)
+}
+
+// ExtractFile copies a single file from the archive read by vidReader into dstDir
+func ExtractFile(file VidFileMeta, vidReader io.ReaderAt, dstDir string) error {
+	return rawarchive.ExtractFile(file, vidReader, dstDir)
+}
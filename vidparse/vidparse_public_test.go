@@ -0,0 +1,167 @@
+package vidparse_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/netscrn/homm3utils/vidparse"
+)
+
+var testVideos = []struct {
+	name    string
+	content []byte
+}{
+	{"H3Intro.smk", []byte("SMK2 intro video")},
+	{"CREDITS.BIK", []byte("BIKi credits, a bit longer")},
+	{"lose.smk", []byte("SMK2 lose")},
+}
+
+// buildTestVid stores contents in reverse order, so table order doesn't match content order like in game archives
+func buildTestVid(t *testing.T) []byte {
+	t.Helper()
+	var table, content bytes.Buffer
+	binary.Write(&table, binary.LittleEndian, uint32(len(testVideos)))
+	contentStart := 4 + 44*len(testVideos)
+	offsets := make([]int, len(testVideos))
+	for i := len(testVideos) - 1; i >= 0; i-- {
+		offsets[i] = contentStart + content.Len()
+		content.Write(testVideos[i].content)
+	}
+	for i, video := range testVideos {
+		name := make([]byte, 40)
+		copy(name, video.name)
+		table.Write(name)
+		binary.Write(&table, binary.LittleEndian, uint32(offsets[i]))
+	}
+	return append(table.Bytes(), content.Bytes()...)
+}
+
+func writeTestVid(t *testing.T) string {
+	t.Helper()
+	pathToVid := filepath.Join(t.TempDir(), "test.vid")
+	err := os.WriteFile(pathToVid, buildTestVid(t), 0644)
+	if err != nil {
+		t.Fatalf("Can't write test vid archive: %v", err)
+	}
+	return pathToVid
+}
+
+func TestLoadVidArchiveMetaFromVidFile(t *testing.T) {
+	vam, err := vidparse.LoadVidArchiveMetaFromVidFile(writeTestVid(t))
+	if err != nil {
+		t.Fatalf("Can't load vid archive meta: %v", err)
+	}
+	if int(vam.NumberOfFiles) != len(testVideos) || len(vam.Files) != len(testVideos) {
+		t.Fatalf("Wrong number of files: %d", vam.NumberOfFiles)
+	}
+	for i, video := range testVideos {
+		if vam.Files[i].Name != video.name {
+			t.Errorf("Wrong name of file [%d]: %q, expected %q", i, vam.Files[i].Name, video.name)
+		}
+		if int(vam.Files[i].Size) != len(video.content) {
+			t.Errorf("Wrong size of file(%s): %d, expected %d", video.name, vam.Files[i].Size, len(video.content))
+		}
+		content, err := vam.ReadFile(video.name)
+		if err != nil {
+			t.Fatalf("Can't read file(%s): %v", video.name, err)
+		}
+		if !bytes.Equal(content, video.content) {
+			t.Errorf("Wrong content of file(%s): %q", video.name, content)
+		}
+	}
+	if _, err := vam.GetFile("h3intro.SMK"); err != nil {
+		t.Errorf("Can't get file ignoring case: %v", err)
+	}
+}
+
+func TestParseVidRejectsBadOffsets(t *testing.T) {
+	vid := buildTestVid(t)
+	binary.LittleEndian.PutUint32(vid[4+40:], uint32(len(vid)+1))
+	_, err := vidparse.ParseVid(bytes.NewReader(vid), int64(len(vid)))
+	if err == nil {
+		t.Error("Archive with offset after its end is parsed")
+	}
+	binary.LittleEndian.PutUint32(vid[4+40:], 8)
+	_, err = vidparse.ParseVid(bytes.NewReader(vid), int64(len(vid)))
+	if err == nil {
+		t.Error("Archive with offset inside its table is parsed")
+	}
+}
+
+func TestParseVidNames(t *testing.T) {
+	vid := buildTestVid(t)
+	fullName := bytes.Repeat([]byte("n"), 40)
+	copy(vid[4:], fullName)
+	vam, err := vidparse.ParseVid(bytes.NewReader(vid), int64(len(vid)))
+	if err != nil {
+		t.Fatalf("Can't parse vid archive with a name taking the whole field: %v", err)
+	}
+	if vam.Files[0].Name != string(fullName) {
+		t.Errorf("Wrong name taking the whole field: %q", vam.Files[0].Name)
+	}
+
+	for _, name := range []string{"", ".", "..", "../evil.smk", "..\\evil.smk"} {
+		copy(vid[4:], make([]byte, 40))
+		copy(vid[4:], name)
+		_, err = vidparse.ParseVid(bytes.NewReader(vid), int64(len(vid)))
+		if err == nil {
+			t.Errorf("Archive with file name %q is parsed", name)
+		}
+	}
+}
+
+func TestVidArchiveMetaFS(t *testing.T) {
+	vid := buildTestVid(t)
+	vam, err := vidparse.ParseVid(bytes.NewReader(vid), int64(len(vid)))
+	if err != nil {
+		t.Fatalf("Can't parse vid archive: %v", err)
+	}
+	names := make([]string, 0, len(testVideos))
+	for _, video := range testVideos {
+		names = append(names, video.name)
+	}
+	err = fstest.TestFS(vam.FS(), names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractVidFiles(t *testing.T) {
+	pathToVid := writeTestVid(t)
+	vam, err := vidparse.LoadVidArchiveMetaFromVidFile(pathToVid)
+	if err != nil {
+		t.Fatalf("Can't load vid archive meta: %v", err)
+	}
+	dstDir := t.TempDir()
+	err = vidparse.ExtractVidFiles(vam, dstDir, 0)
+	if err != nil {
+		t.Fatalf("Can't extract vid archive: %v", err)
+	}
+	for _, video := range testVideos {
+		content, err := os.ReadFile(filepath.Join(dstDir, video.name))
+		if err != nil {
+			t.Fatalf("Can't read extracted file: %v", err)
+		}
+		if !bytes.Equal(content, video.content) {
+			t.Errorf("Extracted file(%s) differs from original", video.name)
+		}
+	}
+
+	jsonDir := t.TempDir()
+	err = vam.WriteJsonFile(jsonDir, "test.json")
+	if err != nil {
+		t.Fatalf("Can't write vid archive meta json: %v", err)
+	}
+	loaded, err := vidparse.LoadVidArchiveMetaFromJson(filepath.Join(jsonDir, "test.json"))
+	if err != nil {
+		t.Fatalf("Can't load vid archive meta from json: %v", err)
+	}
+	if loaded.ArchiveFilePath != pathToVid || !reflect.DeepEqual(loaded.Files, vam.Files) {
+		t.Errorf("Loaded meta differs from written one: %+v", loaded)
+	}
+}